@@ -16,7 +16,10 @@ limitations under the License.
 
 package v1alpha1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 type LocalStorageClass struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -37,12 +40,68 @@ type LocalStorageClassSpec struct {
 	VolumeBindingMode string                    `json:"volumeBindingMode"`
 	LVM               *LocalStorageClassLVMSpec `json:"lvm,omitempty"`
 	FSType            string                    `json:"fsType,omitempty"`
+	// MkfsOptions are passed to mkfs of the FSType, a flag with its value
+	// each, and MountOptions become the mountOptions of the StorageClass. Both
+	// are limited to the options the webhook allows for the FSType.
+	MkfsOptions  []string `json:"mkfsOptions,omitempty"`
+	MountOptions []string `json:"mountOptions,omitempty"`
+	// FsckPolicy is how the node plugin checks an existing filesystem before
+	// mounting it: None, Preen or ForceOnDirty. Empty leaves it to the fsck -a
+	// of mount-utils, as for the classes created before the field.
+	FsckPolicy string `json:"fsckPolicy,omitempty"`
+	// Encryption, when set, makes the volumes of the class LUKS2 devices.
+	Encryption *LocalStorageClassEncryptionSpec `json:"encryption,omitempty"`
+	// IOLimits caps the IO of every volume of the class. A VolumeAttributesClass
+	// of the claim overrides them volume by volume.
+	IOLimits *LocalStorageClassIOLimitsSpec `json:"ioLimits,omitempty"`
+	// Autoscaling, when set, expands the filesystem volumes of the class as
+	// they fill up.
+	Autoscaling *LocalStorageClassAutoscalingSpec `json:"autoscaling,omitempty"`
+}
+
+// LocalStorageClassAutoscalingSpec makes the node plugin raise the request of
+// a PersistentVolumeClaim by Increment, up to MaxSize, once its filesystem is
+// used above ThresholdPercent.
+type LocalStorageClassAutoscalingSpec struct {
+	ThresholdPercent int32             `json:"thresholdPercent"`
+	Increment        resource.Quantity `json:"increment"`
+	MaxSize          resource.Quantity `json:"maxSize"`
+}
+
+// LocalStorageClassIOLimitsSpec is enforced through the cgroup v2 io.max of the
+// pods using a volume. A limit left out is no limit.
+type LocalStorageClassIOLimitsSpec struct {
+	ReadIOPS            int64              `json:"readIOPS,omitempty"`
+	WriteIOPS           int64              `json:"writeIOPS,omitempty"`
+	ReadBytesPerSecond  *resource.Quantity `json:"readBytesPerSecond,omitempty"`
+	WriteBytesPerSecond *resource.Quantity `json:"writeBytesPerSecond,omitempty"`
+}
+
+// LocalStorageClassEncryptionSpec selects where the passphrases of encrypted
+// volumes come from.
+type LocalStorageClassEncryptionSpec struct {
+	// KeySecret references the Secret whose "key" is the passphrase of every
+	// volume of the class. When it is not set, every volume gets a passphrase
+	// of its own, generated into a Secret in the module namespace.
+	KeySecret *LocalStorageClassSecretReference `json:"keySecret,omitempty"`
+}
+
+type LocalStorageClassSecretReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
 }
 
 type LocalStorageClassLVMSpec struct {
 	Type          string                         `json:"type"`
 	Thick         *LocalStorageClassLVMThickSpec `json:"thick,omitempty"`
 	VolumeCleanup string                         `json:"volumeCleanup,omitempty"`
+	// LVNameTemplate is the Go template the names of the logical volumes on
+	// the nodes are rendered from. Empty means they are named after the
+	// PersistentVolume, like the LVMLogicalVolume resources always are.
+	LVNameTemplate string `json:"lvNameTemplate,omitempty"`
+	// Discard returns the blocks freed inside the thin volumes of the class to
+	// their pool.
+	Discard *LocalStorageClassLVMDiscardSpec `json:"discard,omitempty"`
 	// LVMVolumeGroups is the list of entries that select the LVMVolumeGroup
 	// resources where PersistentVolumes will be created. Each entry either names
 	// a single LVMVolumeGroup (Name) or selects LVMVolumeGroups by their labels
@@ -95,6 +154,16 @@ type LocalStorageClassLVG struct {
 	Thin *LocalStorageClassLVMThinPoolSpec `json:"thin,omitempty"`
 }
 
+// LocalStorageClassLVMDiscardSpec selects how the filesystems of thin volumes
+// discard freed blocks: on every delete with the Online discard mount option,
+// or in batches with fstrim run by the node plugin every Interval, on at most
+// Concurrency volumes of the class of a node at a time.
+type LocalStorageClassLVMDiscardSpec struct {
+	Mode        string `json:"mode"`
+	Interval    string `json:"interval,omitempty"`
+	Concurrency int32  `json:"concurrency,omitempty"`
+}
+
 type LocalStorageClassLVMThinPoolSpec struct {
 	PoolName string `json:"poolName"`
 }
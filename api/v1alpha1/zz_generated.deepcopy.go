@@ -53,6 +53,31 @@ func (in *LocalStorageClassSpec) DeepCopyInto(out *LocalStorageClassSpec) {
 		*out = new(LocalStorageClassLVMSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.MkfsOptions != nil {
+		in, out := &in.MkfsOptions, &out.MkfsOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MountOptions != nil {
+		in, out := &in.MountOptions, &out.MountOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(LocalStorageClassEncryptionSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IOLimits != nil {
+		in, out := &in.IOLimits, &out.IOLimits
+		*out = new(LocalStorageClassIOLimitsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(LocalStorageClassAutoscalingSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is a deepcopy function, copying the receiver, creating a new LocalStorageClassSpec.
@@ -73,6 +98,11 @@ func (in *LocalStorageClassLVMSpec) DeepCopyInto(out *LocalStorageClassLVMSpec)
 		*out = new(LocalStorageClassLVMThickSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Discard != nil {
+		in, out := &in.Discard, &out.Discard
+		*out = new(LocalStorageClassLVMDiscardSpec)
+		**out = **in
+	}
 	if in.LVMVolumeGroups != nil {
 		in, out := &in.LVMVolumeGroups, &out.LVMVolumeGroups
 		*out = make([]LocalStorageClassLVG, len(*in))
@@ -92,6 +122,98 @@ func (in *LocalStorageClassLVMSpec) DeepCopy() *LocalStorageClassLVMSpec {
 	return out
 }
 
+// DeepCopyInto is a deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalStorageClassEncryptionSpec) DeepCopyInto(out *LocalStorageClassEncryptionSpec) {
+	*out = *in
+	if in.KeySecret != nil {
+		in, out := &in.KeySecret, &out.KeySecret
+		*out = new(LocalStorageClassSecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is a deepcopy function, copying the receiver, creating a new LocalStorageClassEncryptionSpec.
+func (in *LocalStorageClassEncryptionSpec) DeepCopy() *LocalStorageClassEncryptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalStorageClassEncryptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is a deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalStorageClassSecretReference) DeepCopyInto(out *LocalStorageClassSecretReference) {
+	*out = *in
+}
+
+// DeepCopy is a deepcopy function, copying the receiver, creating a new LocalStorageClassSecretReference.
+func (in *LocalStorageClassSecretReference) DeepCopy() *LocalStorageClassSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalStorageClassSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is a deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalStorageClassIOLimitsSpec) DeepCopyInto(out *LocalStorageClassIOLimitsSpec) {
+	*out = *in
+	if in.ReadBytesPerSecond != nil {
+		in, out := &in.ReadBytesPerSecond, &out.ReadBytesPerSecond
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.WriteBytesPerSecond != nil {
+		in, out := &in.WriteBytesPerSecond, &out.WriteBytesPerSecond
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is a deepcopy function, copying the receiver, creating a new LocalStorageClassIOLimitsSpec.
+func (in *LocalStorageClassIOLimitsSpec) DeepCopy() *LocalStorageClassIOLimitsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalStorageClassIOLimitsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is a deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalStorageClassAutoscalingSpec) DeepCopyInto(out *LocalStorageClassAutoscalingSpec) {
+	*out = *in
+	out.Increment = in.Increment.DeepCopy()
+	out.MaxSize = in.MaxSize.DeepCopy()
+}
+
+// DeepCopy is a deepcopy function, copying the receiver, creating a new LocalStorageClassAutoscalingSpec.
+func (in *LocalStorageClassAutoscalingSpec) DeepCopy() *LocalStorageClassAutoscalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalStorageClassAutoscalingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is a deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalStorageClassLVMDiscardSpec) DeepCopyInto(out *LocalStorageClassLVMDiscardSpec) {
+	*out = *in
+}
+
+// DeepCopy is a deepcopy function, copying the receiver, creating a new LocalStorageClassLVMDiscardSpec.
+func (in *LocalStorageClassLVMDiscardSpec) DeepCopy() *LocalStorageClassLVMDiscardSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalStorageClassLVMDiscardSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is a deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LocalStorageClassLVG) DeepCopyInto(out *LocalStorageClassLVG) {
 	*out = *in
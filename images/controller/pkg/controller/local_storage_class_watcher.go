@@ -65,6 +65,50 @@ const (
 	LVMVolumeGroupsParamKey      = LocalStorageClassProvisioner + "/lvm-volume-groups"
 	LVMThickContiguousParamKey   = LocalStorageClassProvisioner + "/lvm-thick-contiguous"
 	LVMVolumeCleanupParamKey     = LocalStorageClassProvisioner + "/lvm-volume-cleanup"
+	LVMLVNameTemplateParamKey    = LocalStorageClassProvisioner + "/lvm-lv-name-template"
+	EncryptionParamKey           = LocalStorageClassProvisioner + "/encryption"
+	// MkfsOptionsParamKey holds spec.mkfsOptions joined with spaces, which the
+	// CSI driver splits into the arguments of mkfs.
+	MkfsOptionsParamKey = LocalStorageClassProvisioner + "/mkfs-options"
+	FsckPolicyParamKey  = LocalStorageClassProvisioner + "/fsck-policy"
+
+	// IO limits of the volumes. The CSI driver reads the same keys from the
+	// parameters of a VolumeAttributesClass, see images/sds-local-volume-csi/pkg/qos.
+	ReadIOPSParamKey  = LocalStorageClassProvisioner + "/read-iops"
+	WriteIOPSParamKey = LocalStorageClassProvisioner + "/write-iops"
+	ReadBPSParamKey   = LocalStorageClassProvisioner + "/read-bytes-per-second"
+	WriteBPSParamKey  = LocalStorageClassProvisioner + "/write-bytes-per-second"
+
+	// Returning freed blocks of thin volumes to their pool. The interval and
+	// the concurrency are set for the Periodic mode only.
+	DiscardParamKey            = LocalStorageClassProvisioner + "/discard"
+	DiscardIntervalParamKey    = LocalStorageClassProvisioner + "/discard-interval"
+	DiscardConcurrencyParamKey = LocalStorageClassProvisioner + "/discard-concurrency"
+
+	// Values of DiscardParamKey.
+	DiscardOnline   = "Online"
+	DiscardPeriodic = "Periodic"
+
+	DefaultDiscardInterval    = "24h"
+	DefaultDiscardConcurrency = 1
+
+	// The external-provisioner resolves these into the secret references of
+	// the PersistentVolume, and the kubelet passes the Secrets they name to
+	// NodeStageVolume and NodeExpandVolume.
+	NodeStageSecretNameParamKey       = "csi.storage.k8s.io/node-stage-secret-name"
+	NodeStageSecretNamespaceParamKey  = "csi.storage.k8s.io/node-stage-secret-namespace"
+	NodeExpandSecretNameParamKey      = "csi.storage.k8s.io/node-expand-secret-name"
+	NodeExpandSecretNamespaceParamKey = "csi.storage.k8s.io/node-expand-secret-namespace"
+
+	// Values of EncryptionParamKey.
+	EncryptionSharedKey    = "SharedKey"
+	EncryptionPerVolumeKey = "PerVolumeKey"
+
+	// The per-volume passphrases are generated by the CSI controller into
+	// Secrets named after the volume in the module namespace. Restated in
+	// images/sds-local-volume-csi/pkg/encryption, which creates them.
+	EncryptionKeySecretNamePrefix = "sds-local-volume-luks-"
+	EncryptionKeySecretNamespace  = "d8-sds-local-volume"
 
 	FSTypeParamKey = "csi.storage.k8s.io/fstype"
 	DefaultFSType  = "ext4"
@@ -20,9 +20,11 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"reflect"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
@@ -407,6 +409,45 @@ func hasSCDiff(sc *v1.StorageClass, lsc *slv.LocalStorageClass, effectiveLVGs []
 		return true, nil
 	}
 
+	if lsc.Spec.LVM.LVNameTemplate != sc.Parameters[LVMLVNameTemplateParamKey] {
+		return true, nil
+	}
+
+	for key, value := range encryptionParams(lsc) {
+		if sc.Parameters[key] != value {
+			return true, nil
+		}
+	}
+	if lsc.Spec.Encryption == nil && sc.Parameters[EncryptionParamKey] != "" {
+		return true, nil
+	}
+
+	ioLimits := ioLimitsParams(lsc)
+	for _, key := range []string{ReadIOPSParamKey, WriteIOPSParamKey, ReadBPSParamKey, WriteBPSParamKey} {
+		if sc.Parameters[key] != ioLimits[key] {
+			return true, nil
+		}
+	}
+
+	if strings.Join(lsc.Spec.MkfsOptions, " ") != sc.Parameters[MkfsOptionsParamKey] {
+		return true, nil
+	}
+
+	if !slices.Equal(lsc.Spec.MountOptions, sc.MountOptions) {
+		return true, nil
+	}
+
+	if lsc.Spec.FsckPolicy != sc.Parameters[FsckPolicyParamKey] {
+		return true, nil
+	}
+
+	discard := discardParams(lsc)
+	for _, key := range []string{DiscardParamKey, DiscardIntervalParamKey, DiscardConcurrencyParamKey} {
+		if sc.Parameters[key] != discard[key] {
+			return true, nil
+		}
+	}
+
 	if !labelsMatchLSC(sc.Labels, lsc.Labels, ignoredLabelPrefixes) {
 		return true, nil
 	}
@@ -678,6 +719,22 @@ func configureStorageClass(lsc *slv.LocalStorageClass, effectiveLVGs []slv.Local
 		params[LVMVolumeCleanupParamKey] = lsc.Spec.LVM.VolumeCleanup
 	}
 
+	if lsc.Spec.LVM.LVNameTemplate != "" {
+		params[LVMLVNameTemplateParamKey] = lsc.Spec.LVM.LVNameTemplate
+	}
+
+	maps.Copy(params, encryptionParams(lsc))
+	maps.Copy(params, ioLimitsParams(lsc))
+	maps.Copy(params, discardParams(lsc))
+
+	if len(lsc.Spec.MkfsOptions) > 0 {
+		params[MkfsOptionsParamKey] = strings.Join(lsc.Spec.MkfsOptions, " ")
+	}
+
+	if lsc.Spec.FsckPolicy != "" {
+		params[FsckPolicyParamKey] = lsc.Spec.FsckPolicy
+	}
+
 	sc := &v1.StorageClass{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       StorageClassKind,
@@ -696,6 +753,7 @@ func configureStorageClass(lsc *slv.LocalStorageClass, effectiveLVGs []slv.Local
 		ReclaimPolicy:        &reclaimPolicy,
 		AllowVolumeExpansion: &AllowVolumeExpansion,
 		VolumeBindingMode:    &volumeBindingMode,
+		MountOptions:         lsc.Spec.MountOptions,
 	}
 
 	filteredLabels := filterLabelsForStorageClass(lsc.Labels, ignoredLabelPrefixes)
@@ -1228,3 +1286,84 @@ func updateStorageClass(lsc *slv.LocalStorageClass, oldSC *v1.StorageClass, effe
 
 	return newSC, nil
 }
+
+// encryptionParams returns the StorageClass parameters of an encrypted
+// LocalStorageClass, and nil for a plain one. The same Secret serves staging and
+// expansion: growing a LUKS device may need its passphrase.
+func encryptionParams(lsc *slv.LocalStorageClass) map[string]string {
+	if lsc.Spec.Encryption == nil {
+		return nil
+	}
+
+	mode := EncryptionPerVolumeKey
+	secretName := EncryptionKeySecretNamePrefix + "${pv.name}"
+	secretNamespace := EncryptionKeySecretNamespace
+	if ref := lsc.Spec.Encryption.KeySecret; ref != nil {
+		mode = EncryptionSharedKey
+		secretName = ref.Name
+		secretNamespace = ref.Namespace
+	}
+
+	return map[string]string{
+		EncryptionParamKey:                mode,
+		NodeStageSecretNameParamKey:       secretName,
+		NodeStageSecretNamespaceParamKey:  secretNamespace,
+		NodeExpandSecretNameParamKey:      secretName,
+		NodeExpandSecretNamespaceParamKey: secretNamespace,
+	}
+}
+
+// ioLimitsParams returns the StorageClass parameters of the IO limits of a
+// LocalStorageClass, leaving out the limits that are not set. Bandwidths are
+// passed as plain numbers of bytes.
+func ioLimitsParams(lsc *slv.LocalStorageClass) map[string]string {
+	limits := lsc.Spec.IOLimits
+	if limits == nil {
+		return nil
+	}
+
+	params := make(map[string]string, 4)
+	if limits.ReadIOPS > 0 {
+		params[ReadIOPSParamKey] = strconv.FormatInt(limits.ReadIOPS, 10)
+	}
+	if limits.WriteIOPS > 0 {
+		params[WriteIOPSParamKey] = strconv.FormatInt(limits.WriteIOPS, 10)
+	}
+	if limits.ReadBytesPerSecond != nil && limits.ReadBytesPerSecond.Value() > 0 {
+		params[ReadBPSParamKey] = strconv.FormatInt(limits.ReadBytesPerSecond.Value(), 10)
+	}
+	if limits.WriteBytesPerSecond != nil && limits.WriteBytesPerSecond.Value() > 0 {
+		params[WriteBPSParamKey] = strconv.FormatInt(limits.WriteBytesPerSecond.Value(), 10)
+	}
+
+	return params
+}
+
+// discardParams returns the StorageClass parameters of spec.lvm.discard of a
+// LocalStorageClass, filling in the defaults of the Periodic mode for objects
+// the API server has not defaulted.
+func discardParams(lsc *slv.LocalStorageClass) map[string]string {
+	if lsc.Spec.LVM == nil || lsc.Spec.LVM.Discard == nil {
+		return nil
+	}
+
+	discard := lsc.Spec.LVM.Discard
+	if discard.Mode != DiscardPeriodic {
+		return map[string]string{DiscardParamKey: discard.Mode}
+	}
+
+	interval := discard.Interval
+	if interval == "" {
+		interval = DefaultDiscardInterval
+	}
+	concurrency := discard.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultDiscardConcurrency
+	}
+
+	return map[string]string{
+		DiscardParamKey:            discard.Mode,
+		DiscardIntervalParamKey:    interval,
+		DiscardConcurrencyParamKey: strconv.Itoa(int(concurrency)),
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	slv "github.com/deckhouse/sds-local-volume/api/v1alpha1"
+)
+
+// newTestLSC returns a thick LocalStorageClass on lvg-1 with each of options
+// applied to it.
+func newTestLSC(options ...func(*slv.LocalStorageClass)) *slv.LocalStorageClass {
+	lsc := &slv.LocalStorageClass{
+		ObjectMeta: metav1.ObjectMeta{Name: testLSCName},
+		Spec: slv.LocalStorageClassSpec{
+			ReclaimPolicy:     "Delete",
+			VolumeBindingMode: "WaitForFirstConsumer",
+			LVM: &slv.LocalStorageClassLVMSpec{
+				Type:            LVMThickType,
+				LVMVolumeGroups: []slv.LocalStorageClassLVG{{Name: "lvg-1"}},
+			},
+		},
+	}
+	for _, option := range options {
+		option(lsc)
+	}
+
+	return lsc
+}
+
+func withThinPool(lsc *slv.LocalStorageClass) {
+	lsc.Spec.LVM.Type = LVMThinType
+	lsc.Spec.LVM.LVMVolumeGroups = []slv.LocalStorageClassLVG{{Name: "lvg-1", Thin: &slv.LocalStorageClassLVMThinPoolSpec{PoolName: "pool-1"}}}
+}
+
+// Every parameter below reaches the CSI driver through the StorageClass.
+// StorageClass parameters and mount options are immutable, so a changed
+// LocalStorageClass must make hasSCDiff ask for the StorageClass to be
+// recreated.
+func TestStorageClassParameters(t *testing.T) {
+	bandwidth := resource.MustParse("100Mi")
+
+	for _, tc := range []struct {
+		name         string
+		lsc          *slv.LocalStorageClass
+		params       map[string]string
+		mountOptions []string
+		// changes each turn the LocalStorageClass into one the StorageClass
+		// no longer matches.
+		changes map[string]func(*slv.LocalStorageClass)
+	}{
+		{
+			name: "lv name template",
+			lsc: newTestLSC(func(lsc *slv.LocalStorageClass) {
+				lsc.Spec.LVM.LVNameTemplate = "{{.Namespace}}-{{.PVCName}}-{{.Short}}"
+			}),
+			params: map[string]string{LVMLVNameTemplateParamKey: "{{.Namespace}}-{{.PVCName}}-{{.Short}}"},
+			changes: map[string]func(*slv.LocalStorageClass){
+				"changed": func(lsc *slv.LocalStorageClass) { lsc.Spec.LVM.LVNameTemplate = "fast-{{.Short}}" },
+				"removed": func(lsc *slv.LocalStorageClass) { lsc.Spec.LVM.LVNameTemplate = "" },
+			},
+		},
+		{
+			name: "per-volume encryption keys",
+			lsc: newTestLSC(func(lsc *slv.LocalStorageClass) {
+				lsc.Spec.Encryption = &slv.LocalStorageClassEncryptionSpec{}
+			}),
+			params: map[string]string{
+				EncryptionParamKey:                EncryptionPerVolumeKey,
+				NodeStageSecretNameParamKey:       "sds-local-volume-luks-${pv.name}",
+				NodeStageSecretNamespaceParamKey:  "d8-sds-local-volume",
+				NodeExpandSecretNameParamKey:      "sds-local-volume-luks-${pv.name}",
+				NodeExpandSecretNamespaceParamKey: "d8-sds-local-volume",
+			},
+			changes: map[string]func(*slv.LocalStorageClass){
+				"removed": func(lsc *slv.LocalStorageClass) { lsc.Spec.Encryption = nil },
+			},
+		},
+		{
+			name: "shared encryption key",
+			lsc: newTestLSC(func(lsc *slv.LocalStorageClass) {
+				lsc.Spec.Encryption = &slv.LocalStorageClassEncryptionSpec{
+					KeySecret: &slv.LocalStorageClassSecretReference{Name: "luks-key", Namespace: "storage"},
+				}
+			}),
+			params: map[string]string{
+				EncryptionParamKey:                EncryptionSharedKey,
+				NodeStageSecretNameParamKey:       "luks-key",
+				NodeStageSecretNamespaceParamKey:  "storage",
+				NodeExpandSecretNameParamKey:      "luks-key",
+				NodeExpandSecretNamespaceParamKey: "storage",
+			},
+			changes: map[string]func(*slv.LocalStorageClass){
+				"per-volume": func(lsc *slv.LocalStorageClass) { lsc.Spec.Encryption.KeySecret = nil },
+				"removed":    func(lsc *slv.LocalStorageClass) { lsc.Spec.Encryption = nil },
+			},
+		},
+		{
+			name: "io limits",
+			lsc: newTestLSC(func(lsc *slv.LocalStorageClass) {
+				lsc.Spec.IOLimits = &slv.LocalStorageClassIOLimitsSpec{WriteIOPS: 500, ReadBytesPerSecond: &bandwidth}
+			}),
+			params: map[string]string{
+				ReadIOPSParamKey:  "",
+				WriteIOPSParamKey: "500",
+				ReadBPSParamKey:   "104857600",
+				WriteBPSParamKey:  "",
+			},
+			changes: map[string]func(*slv.LocalStorageClass){
+				"limit added": func(lsc *slv.LocalStorageClass) { lsc.Spec.IOLimits.ReadIOPS = 1000 },
+				"removed":     func(lsc *slv.LocalStorageClass) { lsc.Spec.IOLimits = nil },
+			},
+		},
+		{
+			name: "discard",
+			lsc: newTestLSC(withThinPool, func(lsc *slv.LocalStorageClass) {
+				lsc.Spec.LVM.Discard = &slv.LocalStorageClassLVMDiscardSpec{Mode: DiscardPeriodic}
+			}),
+			params: map[string]string{
+				DiscardParamKey:            DiscardPeriodic,
+				DiscardIntervalParamKey:    DefaultDiscardInterval,
+				DiscardConcurrencyParamKey: "1",
+			},
+			changes: map[string]func(*slv.LocalStorageClass){
+				"online":  func(lsc *slv.LocalStorageClass) { lsc.Spec.LVM.Discard.Mode = DiscardOnline },
+				"removed": func(lsc *slv.LocalStorageClass) { lsc.Spec.LVM.Discard = nil },
+			},
+		},
+		{
+			name: "mkfs options",
+			lsc: newTestLSC(func(lsc *slv.LocalStorageClass) {
+				lsc.Spec.MkfsOptions = []string{"-m 0", "-E lazy_itable_init=0"}
+			}),
+			params: map[string]string{MkfsOptionsParamKey: "-m 0 -E lazy_itable_init=0"},
+			changes: map[string]func(*slv.LocalStorageClass){
+				"removed": func(lsc *slv.LocalStorageClass) { lsc.Spec.MkfsOptions = nil },
+			},
+		},
+		{
+			name: "mount options",
+			lsc: newTestLSC(func(lsc *slv.LocalStorageClass) {
+				lsc.Spec.MountOptions = []string{"noatime", "discard"}
+			}),
+			mountOptions: []string{"noatime", "discard"},
+			changes: map[string]func(*slv.LocalStorageClass){
+				"changed": func(lsc *slv.LocalStorageClass) { lsc.Spec.MountOptions = []string{"noatime"} },
+				"removed": func(lsc *slv.LocalStorageClass) { lsc.Spec.MountOptions = nil },
+			},
+		},
+		{
+			name: "fsck policy",
+			lsc: newTestLSC(func(lsc *slv.LocalStorageClass) {
+				lsc.Spec.FsckPolicy = "Preen"
+			}),
+			params: map[string]string{FsckPolicyParamKey: "Preen"},
+			changes: map[string]func(*slv.LocalStorageClass){
+				"changed": func(lsc *slv.LocalStorageClass) { lsc.Spec.FsckPolicy = "None" },
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lvgs := tc.lsc.Spec.LVM.LVMVolumeGroups
+
+			sc, err := configureStorageClass(tc.lsc, lvgs, nil)
+			if err != nil {
+				t.Fatalf("configureStorageClass: %v", err)
+			}
+			for key, want := range tc.params {
+				if got := sc.Parameters[key]; got != want {
+					t.Errorf("parameter %s = %q, want %q", key, got, want)
+				}
+			}
+			if !slices.Equal(sc.MountOptions, tc.mountOptions) {
+				t.Errorf("mountOptions = %q, want %q", sc.MountOptions, tc.mountOptions)
+			}
+
+			if diff, err := hasSCDiff(sc, tc.lsc, lvgs, nil); err != nil || diff {
+				t.Errorf("hasSCDiff against its own StorageClass = %v, %v, want false", diff, err)
+			}
+			for name, change := range tc.changes {
+				changed := tc.lsc.DeepCopy()
+				change(changed)
+				if diff, err := hasSCDiff(sc, changed, lvgs, nil); err != nil || !diff {
+					t.Errorf("hasSCDiff %s = %v, %v, want true", name, diff, err)
+				}
+			}
+		})
+	}
+}
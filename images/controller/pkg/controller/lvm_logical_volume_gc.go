@@ -74,6 +74,12 @@ const (
 	// collector does — an acknowledged volume is still reclaimed once somebody
 	// deletes it — it only moves the volume out of the state the alert reads.
 	RetainAcknowledgedAnnotation = "storage.deckhouse.io/retain-acknowledged"
+
+	// PVCNameAnnotation and PVCNamespaceAnnotation name the claim an
+	// LVMLogicalVolume was provisioned for. The CSI driver sets them at
+	// CreateVolume; images/sds-local-volume-csi/pkg/utils holds the other copy.
+	PVCNameAnnotation      = "storage.deckhouse.io/pvc-name"
+	PVCNamespaceAnnotation = "storage.deckhouse.io/pvc-namespace"
 )
 
 // errAgentFinalizerAbsent is returned when the live object turns out to carry no
@@ -587,6 +593,8 @@ func (g *llvGarbageCollector) sweepVolumes(
 			Type:           llvType(llv),
 			State:          state,
 			Reason:         reason,
+			PVCName:        llv.Annotations[PVCNameAnnotation],
+			PVCNamespace:   llv.Annotations[PVCNamespaceAnnotation],
 			AllocatedBytes: llvAllocatedBytes(llv),
 		})
 	}
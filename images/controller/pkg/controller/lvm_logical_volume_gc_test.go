@@ -218,6 +218,18 @@ func retainAcknowledgedOption() llvOption {
 	}
 }
 
+// withClaim records the claim the volume was provisioned for, the way the CSI
+// driver does at CreateVolume.
+func withClaim(namespace, name string) llvOption {
+	return func(llv *snc.LVMLogicalVolume) {
+		if llv.Annotations == nil {
+			llv.Annotations = map[string]string{}
+		}
+		llv.Annotations[PVCNamespaceAnnotation] = namespace
+		llv.Annotations[PVCNameAnnotation] = name
+	}
+}
+
 // withUID pins the identity the write path compares against, so that a spec can
 // make the live object a different one under the same name.
 func withUID(uid string) llvOption {
@@ -856,6 +868,30 @@ func TestSweepOnlyReportsAnOrphanNobodyDeleted(t *testing.T) {
 	)
 }
 
+// TestSweepNamesTheClaimOfAnOrphan checks that an orphan carries the claim it
+// was provisioned for, which is what lets whoever reads the alert tell whose data
+// it is, and that a volume from before the CSI driver recorded it reports none.
+func TestSweepNamesTheClaimOfAnOrphan(t *testing.T) {
+	named := newLLV("pvc-named", withClaim("db", "data-postgres-0"))
+	unnamed := newLLV("pvc-unnamed")
+	gc, _ := newGC(t, []client.Object{named, unnamed}, []client.Object{named, unnamed})
+
+	sweep, _, err := gc.sweep(context.Background())
+	require.NoError(t, err)
+	require.Len(t, sweep.Orphans, 2)
+
+	for _, orphan := range sweep.Orphans {
+		switch orphan.Name {
+		case "pvc-named":
+			assert.Equal(t, "db", orphan.PVCNamespace)
+			assert.Equal(t, "data-postgres-0", orphan.PVCName)
+		case "pvc-unnamed":
+			assert.Empty(t, orphan.PVCNamespace)
+			assert.Empty(t, orphan.PVCName)
+		}
+	}
+}
+
 // TestSweepWaitsOutTheGracePeriod checks that a fresh deletion is left alone for a
 // while — an in-flight DeleteVolume gets to finish on its own — and that the next
 // sweep is scheduled for the moment the period ends rather than for the periodic
@@ -114,6 +114,8 @@ const (
 	LabelKind             = "kind"
 	LabelLVMVolumeGroup   = "lvm_volume_group"
 	LabelLVMLogicalVolume = "lvm_logical_volume"
+	LabelPVCName          = "pvc_name"
+	LabelPVCNamespace     = "pvc_namespace"
 
 	// LabelLVMType is the LVMLogicalVolume's spec.type, and it is what makes the
 	// byte counts readable. The node agent reports LV_SIZE as the actual size, which
@@ -286,8 +288,8 @@ func Register(st metricsstorage.Storage) error {
 
 	if _, err := st.RegisterGauge(
 		OrphanedLVMLogicalVolumeAllocatedBytes,
-		[]string{LabelName, LabelLVMVolumeGroup, LabelLVMType, LabelState, LabelReason},
-		options.WithHelp("Size of a single LVMLogicalVolume whose PersistentVolume no longer exists, in bytes, read per the type label as in "+LVMLogicalVolumeAllocatedBytes+". The reason label says why a reclaim is refused, and is empty when it is not."),
+		[]string{LabelName, LabelLVMVolumeGroup, LabelLVMType, LabelState, LabelReason, LabelPVCName, LabelPVCNamespace},
+		options.WithHelp("Size of a single LVMLogicalVolume whose PersistentVolume no longer exists, in bytes, read per the type label as in "+LVMLogicalVolumeAllocatedBytes+". The reason label says why a reclaim is refused, and is empty when it is not. The pvc_name and pvc_namespace labels name the claim the volume was provisioned for, and are empty for volumes provisioned before the CSI driver recorded it."),
 	); err != nil {
 		return fmt.Errorf("register %s: %w", OrphanedLVMLogicalVolumeAllocatedBytes, err)
 	}
@@ -464,7 +466,11 @@ type OrphanedLVMLogicalVolume struct {
 	State string
 	// Reason is one of the BlockReason* values when State is OrphanStateBlocked,
 	// and empty otherwise.
-	Reason         string
+	Reason string
+	// PVCName and PVCNamespace name the claim the volume was provisioned for, as
+	// recorded on it by the CSI driver. Both are empty for older volumes.
+	PVCName        string
+	PVCNamespace   string
 	AllocatedBytes float64
 }
 
@@ -553,6 +559,8 @@ func (r Recorder) SetLVMLogicalVolumeSweep(sweep LVMLogicalVolumeSweep) {
 			LabelLVMType:        orphan.Type,
 			LabelState:          orphan.State,
 			LabelReason:         orphan.Reason,
+			LabelPVCName:        orphan.PVCName,
+			LabelPVCNamespace:   orphan.PVCNamespace,
 		})
 	}
 
@@ -233,7 +233,7 @@ func TestSetLVMLogicalVolumeSweepPublishesTheWholePicture(t *testing.T) {
 			{Phase: PhaseUnknown, Type: "Thin"}: 0,
 		},
 		Orphans: []OrphanedLVMLogicalVolume{
-			{Name: "pvc-a", LVMVolumeGroup: "vg-0", Type: "Thick", State: OrphanStateActive, AllocatedBytes: 1073741824},
+			{Name: "pvc-a", LVMVolumeGroup: "vg-0", Type: "Thick", State: OrphanStateActive, PVCName: "data-postgres-0", PVCNamespace: "db", AllocatedBytes: 1073741824},
 			{Name: "pvc-b", LVMVolumeGroup: "vg-1", Type: "Thick", State: OrphanStateTerminating, AllocatedBytes: 2147483648},
 			{
 				Name:           "pvc-c",
@@ -263,12 +263,14 @@ func TestSetLVMLogicalVolumeSweepPublishesTheWholePicture(t *testing.T) {
 
 	// An orphan that is not blocked has nothing to explain, so the reason label is
 	// empty rather than carrying a value a rule would have to know to ignore.
-	assert.Contains(t, out, OrphanedLVMLogicalVolumeAllocatedBytes+"{lvm_volume_group=vg-0,name=pvc-a,reason=,state="+OrphanStateActive+",type=Thick,}1073741824")
-	assert.Contains(t, out, OrphanedLVMLogicalVolumeAllocatedBytes+"{lvm_volume_group=vg-1,name=pvc-b,reason=,state="+OrphanStateTerminating+",type=Thick,}2147483648")
+	// The claim is named when the CSI driver recorded it, and left empty for a
+	// volume provisioned before it did.
+	assert.Contains(t, out, OrphanedLVMLogicalVolumeAllocatedBytes+"{lvm_volume_group=vg-0,name=pvc-a,pvc_name=data-postgres-0,pvc_namespace=db,reason=,state="+OrphanStateActive+",type=Thick,}1073741824")
+	assert.Contains(t, out, OrphanedLVMLogicalVolumeAllocatedBytes+"{lvm_volume_group=vg-1,name=pvc-b,pvc_name=,pvc_namespace=,reason=,state="+OrphanStateTerminating+",type=Thick,}2147483648")
 
 	// A blocked orphan names the refusal, so that neither a dashboard nor an
 	// on-call engineer has to grep the controller log for it.
-	assert.Contains(t, out, OrphanedLVMLogicalVolumeAllocatedBytes+"{lvm_volume_group=vg-1,name=pvc-c,reason="+BlockReasonSnapshotsPresent+",state="+OrphanStateBlocked+",type=Thick,}4294967296")
+	assert.Contains(t, out, OrphanedLVMLogicalVolumeAllocatedBytes+"{lvm_volume_group=vg-1,name=pvc-c,pvc_name=,pvc_namespace=,reason="+BlockReasonSnapshotsPresent+",state="+OrphanStateBlocked+",type=Thick,}4294967296")
 
 	// A blocked snapshot is reported the same way, and names the volume it is in
 	// turn keeping blocked.
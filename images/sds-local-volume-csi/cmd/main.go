@@ -23,11 +23,13 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	sv1 "k8s.io/api/storage/v1"
 	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -36,9 +38,11 @@ import (
 	slv "github.com/deckhouse/sds-local-volume/api/v1alpha1"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/config"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/driver"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/events"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/kubutils"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/monitoring"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/tracing"
 	snc "github.com/deckhouse/sds-node-configurator/api/v1alpha1"
 )
 
@@ -53,14 +57,6 @@ var (
 	}
 )
 
-func healthHandler(w http.ResponseWriter, _ *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	_, err := fmt.Fprint(w, "OK")
-	if err != nil {
-		klog.Fatalf("Error while generating healthcheck, err: %s", err.Error())
-	}
-}
-
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -76,7 +72,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	log.Info("starting sds-local-volume-csi", "version", cfgParams.Version)
+	log.Info("starting sds-local-volume-csi", "version", cfgParams.Version, "role", cfgParams.Role)
 
 	kConfig, err := kubutils.KubernetesDefaultConfigCreate()
 	if err != nil {
@@ -99,36 +95,61 @@ func main() {
 		Scheme: scheme,
 	})
 
-	http.HandleFunc("/healthz", healthHandler)
-	http.HandleFunc("/readyz", healthHandler)
-	go func() {
-		err = http.ListenAndServe(cfgParams.HealthProbeBindAddress, nil)
-		if err != nil {
-			log.Error("create probes", logger.Err(err))
-		}
-	}()
-
 	// The driver runs no controller-runtime manager, so it keeps its own registry
 	// and serves it on a dedicated listener. A separate mux keeps /metrics off the
-	// default one used by the health probes above.
+	// default one used by the health probes below.
 	metricStorage := metricsstorage.NewMetricStorage(metricsstorage.WithNewRegistry())
 	if err = monitoring.Register(metricStorage); err != nil {
 		log.Error("unable to register the metrics", logger.Err(err))
 		os.Exit(1)
 	}
+
+	shutdownTracing, err := tracing.Setup(ctx, cfgParams.TracingExporter, cfgParams.TracingOTLPEndpoint, "sds-local-volume-csi")
+	if err != nil {
+		log.Error("unable to set up tracing", logger.Err(err))
+		os.Exit(1)
+	}
+	log.Info("tracing has been set up", "exporter", cfgParams.TracingExporter)
+
+	clientset, err := kubernetes.NewForConfig(kConfig)
+	if err != nil {
+		log.Error("unable to create the kubernetes clientset", logger.Err(err))
+		os.Exit(1)
+	}
+
+	eventComponent := "sds-local-volume-csi"
+	if cfgParams.Role != "" {
+		eventComponent += "-" + cfgParams.Role
+	}
+	eventRecorder, shutdownEvents := events.NewRecorder(cl, clientset.CoreV1().Events(""), eventComponent, cfgParams.NodeName, log)
+	defer shutdownEvents()
+
+	drv, err := driver.NewDriver(cfgParams.CsiAddress, cfgParams.DriverName, cfgParams.Address, &cfgParams.NodeName, cfgParams.Role, log, monitoring.NewRecorder(metricStorage), eventRecorder, cl, cfgParams.ShutdownDrainTimeout, cfgParams.LazyUnmountAfterFailures)
+	if err != nil {
+		log.Error("create NewDriver", logger.Err(err))
+	}
+
+	http.Handle("/healthz", drv.LivenessHandler())
+	http.Handle("/readyz", drv.ReadinessHandler())
+	go func() {
+		err := http.ListenAndServe(cfgParams.HealthProbeBindAddress, nil)
+		if err != nil {
+			log.Error("create probes", logger.Err(err))
+		}
+	}()
+
+	// /debug/inflight shares the metrics listener: it answers the question an
+	// Aborted response or a growing oldest-operation gauge raises, namely which
+	// operation holds the volume and since when.
 	go func() {
 		metricsMux := http.NewServeMux()
 		metricsMux.Handle("/metrics", metricStorage.Handler())
+		metricsMux.Handle("/debug/inflight", drv.InFlightHandler())
 		if err := http.ListenAndServe(cfgParams.MetricsBindAddress, metricsMux); err != nil {
 			log.Error("unable to serve the metrics", logger.Err(err))
 		}
 	}()
-	log.Info("metrics registered", "address", cfgParams.MetricsBindAddress+"/metrics")
-
-	drv, err := driver.NewDriver(cfgParams.CsiAddress, cfgParams.DriverName, cfgParams.Address, &cfgParams.NodeName, log, monitoring.NewRecorder(metricStorage), cl)
-	if err != nil {
-		log.Error("create NewDriver", logger.Err(err))
-	}
+	log.Info("metrics registered", "address", cfgParams.MetricsBindAddress+"/metrics", "inFlightAddress", cfgParams.MetricsBindAddress+"/debug/inflight")
 
 	defer cancel()
 
@@ -137,9 +158,23 @@ func main() {
 	go func() {
 		<-c
 		cancel()
+
+		// The first signal starts the drain; a second one means the caller is not
+		// willing to wait for it.
+		<-c
+		log.Warn("received a second signal, exiting without waiting for in-flight operations")
+		os.Exit(1)
 	}()
 
 	if err := drv.Run(ctx); err != nil {
 		log.Error("[dev.Run]", logger.Err(err))
 	}
+
+	// ctx is cancelled by now; the spans of the drained operations still get a
+	// few seconds to reach the collector.
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Error("unable to flush the spans", logger.Err(err))
+	}
 }
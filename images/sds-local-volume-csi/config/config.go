@@ -20,9 +20,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/driver"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/tracing"
 )
 
 const (
@@ -32,17 +35,41 @@ const (
 	DefaultHealthProbeBindAddress        = ":8081"
 	MetricsBindAddressEnvName            = "METRICS_BIND_ADDRESS"
 	DefaultMetricsBindAddress            = ":8080"
+	ShutdownDrainTimeoutEnvName          = "SHUTDOWN_DRAIN_TIMEOUT"
+	// DefaultShutdownDrainTimeout stays below the default 30s termination grace
+	// period, so the driver gets to log what it abandoned before the kubelet
+	// sends SIGKILL. The chart sets the timeout from the shutdownDrainTimeout
+	// setting and the grace period of the pods 10s above it.
+	DefaultShutdownDrainTimeout = 25 * time.Second
+	// TracingExporterEnvName selects where spans go: none, otlp or stdout.
+	TracingExporterEnvName = "TRACING_EXPORTER"
+	DefaultTracingExporter = tracing.ExporterNone
+	// TracingOTLPEndpointEnvName is the collector URL of the otlp exporter. When
+	// empty, the standard OTEL_EXPORTER_OTLP_ENDPOINT variables apply.
+	TracingOTLPEndpointEnvName = "TRACING_OTLP_ENDPOINT"
+	// LazyUnmountAfterFailuresEnvName is how many times in a row a busy mount
+	// fails to unmount before the node plugin unmounts it lazily. Unset or 0
+	// never does.
+	LazyUnmountAfterFailuresEnvName = "LAZY_UNMOUNT_AFTER_FAILURES"
 )
 
 type Options struct {
 	NodeName               string
+	Role                   string
 	Version                string
 	Loglevel               logger.Verbosity
 	HealthProbeBindAddress string
 	MetricsBindAddress     string
+	ShutdownDrainTimeout   time.Duration
+	TracingExporter        string
+	TracingOTLPEndpoint    string
 	CsiAddress             string
 	DriverName             string
 	Address                string
+
+	// LazyUnmountAfterFailures is 0 when busy mounts are never unmounted
+	// lazily.
+	LazyUnmountAfterFailures int
 }
 
 func NewConfig() (*Options, error) {
@@ -63,6 +90,34 @@ func NewConfig() (*Options, error) {
 		opts.MetricsBindAddress = DefaultMetricsBindAddress
 	}
 
+	opts.ShutdownDrainTimeout = DefaultShutdownDrainTimeout
+	if drainTimeout := os.Getenv(ShutdownDrainTimeoutEnvName); drainTimeout != "" {
+		timeout, err := time.ParseDuration(drainTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("[NewConfig] unable to parse %s env variable: %w", ShutdownDrainTimeoutEnvName, err)
+		}
+		opts.ShutdownDrainTimeout = timeout
+	}
+
+	opts.TracingExporter = os.Getenv(TracingExporterEnvName)
+	if opts.TracingExporter == "" {
+		opts.TracingExporter = DefaultTracingExporter
+	}
+	switch opts.TracingExporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		return nil, fmt.Errorf("[NewConfig] unsupported %s env variable value %q", TracingExporterEnvName, opts.TracingExporter)
+	}
+	opts.TracingOTLPEndpoint = os.Getenv(TracingOTLPEndpointEnvName)
+
+	if failures := os.Getenv(LazyUnmountAfterFailuresEnvName); failures != "" {
+		n, err := strconv.Atoi(failures)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("[NewConfig] %s env variable must be a non-negative integer, have %q", LazyUnmountAfterFailuresEnvName, failures)
+		}
+		opts.LazyUnmountAfterFailures = n
+	}
+
 	loglevel := os.Getenv(LogLevel)
 	if loglevel == "" {
 		opts.Loglevel = logger.DebugLevel
@@ -76,11 +131,18 @@ func NewConfig() (*Options, error) {
 	fl.StringVar(&opts.CsiAddress, "csi-address", "unix:///var/lib/kubelet/plugins/"+driver.DefaultDriverName+"/csi.sock", "CSI address")
 	fl.StringVar(&opts.DriverName, "driver-name", driver.DefaultDriverName, "Name for the driver")
 	fl.StringVar(&opts.Address, "address", driver.DefaultAddress, "Address to serve on")
+	fl.StringVar(&opts.Role, "role", "", "Role the plugin runs in: "+driver.RoleController+" or "+driver.RoleNode+"; empty runs both")
 
 	err := fl.Parse(os.Args[1:])
 	if err != nil {
 		return &opts, err
 	}
 
+	switch opts.Role {
+	case "", driver.RoleController, driver.RoleNode:
+	default:
+		return &opts, fmt.Errorf("[NewConfig] unsupported role %q", opts.Role)
+	}
+
 	return &opts, nil
 }
@@ -0,0 +1,285 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	slv "github.com/deckhouse/sds-local-volume/api/v1alpha1"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/events"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/monitoring"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+)
+
+// autoscalingUsage is the usage of a volume the node plugin reports in the
+// internal.AutoscalingAnnotation of its node. Size is the size of the
+// filesystem, which tells the controller the capacity the usage was taken at.
+type autoscalingUsage struct {
+	Size      int64 `json:"size"`
+	Used      int64 `json:"used"`
+	Available int64 `json:"available"`
+}
+
+// staleUsageMarginPercent is how much of the capacity of a volume its
+// filesystem may take for its own metadata. A report whose filesystem is
+// smaller than that was taken before the last expansion of the volume.
+const staleUsageMarginPercent = 10
+
+// autoscalingSpecs looks up the autoscaling of LocalStorageClasses, once per
+// class in a round of collectVolumeMetrics or autoscaleVolumes.
+type autoscalingSpecs map[string]*slv.LocalStorageClassAutoscalingSpec
+
+func (a autoscalingSpecs) get(ctx context.Context, cl client.Client, log logger.Logger, name string) *slv.LocalStorageClassAutoscalingSpec {
+	if spec, ok := a[name]; ok {
+		return spec
+	}
+
+	lsc := &slv.LocalStorageClass{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: name}, lsc); err != nil {
+		if !kerrors.IsNotFound(err) {
+			log.Warn("unable to get the LocalStorageClass, its volumes are not autoscaled", logger.Err(err), slog.String("localStorageClass", name))
+		}
+		a[name] = nil
+		return nil
+	}
+
+	a[name] = lsc.Spec.Autoscaling
+	return lsc.Spec.Autoscaling
+}
+
+// aboveThreshold tells whether a volume is used above the autoscaling
+// threshold of spec. The size is what df shows: the blocks reserved for root
+// are neither used nor available.
+func aboveThreshold(spec *slv.LocalStorageClassAutoscalingSpec, usage autoscalingUsage) bool {
+	size := usage.Used + usage.Available
+	return spec != nil && size > 0 && usage.Used*100 >= int64(spec.ThresholdPercent)*size
+}
+
+// autoscalingUsageOf returns the usage of a staged filesystem volume to report
+// to the controller, if its LocalStorageClass autoscales it and it is used
+// above the threshold.
+func (d *Driver) autoscalingUsageOf(ctx context.Context, log logger.Logger, volume internal.StagedVolume, usage monitoring.VolumeUsage, specs autoscalingSpecs) (autoscalingUsage, bool) {
+	if volume.Block || volume.ReadOnly || !usage.UsageKnown || volume.LocalStorageClass == "" {
+		return autoscalingUsage{}, false
+	}
+
+	reported := autoscalingUsage{Size: usage.Capacity, Used: usage.Used, Available: usage.Available}
+	return reported, aboveThreshold(specs.get(ctx, d.cl, log, volume.LocalStorageClass), reported)
+}
+
+// reportAutoscaling puts the usage of the volumes to autoscale into the
+// internal.AutoscalingAnnotation of the node, or removes the annotation when
+// there are none. The node plugin cannot change the claims itself. The node is
+// patched only when the report changes.
+func (d *Driver) reportAutoscaling(ctx context.Context, log logger.Logger, usage map[string]autoscalingUsage) {
+	if d.autoscalingReported && maps.Equal(d.autoscalingReport, usage) {
+		return
+	}
+
+	var value any
+	if len(usage) > 0 {
+		data, err := json.Marshal(usage)
+		if err != nil {
+			log.Error("unable to encode the usage of the volumes to autoscale", logger.Err(err))
+			return
+		}
+		value = string(data)
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"annotations": map[string]any{internal.AutoscalingAnnotation: value}},
+	})
+	if err != nil {
+		log.Error("unable to encode the patch of the node", logger.Err(err))
+		return
+	}
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: d.hostID}}
+	if err := d.cl.Patch(ctx, node, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		log.Warn("unable to report the volumes to autoscale", logger.Err(err))
+		return
+	}
+
+	d.autoscalingReport = usage
+	d.autoscalingReported = true
+}
+
+// autoscaleVolumes raises the requests of the claims of the volumes the nodes
+// report above the autoscaling threshold, every volumeMetricsInterval until
+// ctx is cancelled.
+func (d *Driver) autoscaleVolumes(ctx context.Context) {
+	log := d.log.Named("autoscaleVolumes")
+
+	ticker := time.NewTicker(volumeMetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		nodes := &corev1.NodeList{}
+		if err := d.cl.List(ctx, nodes); err != nil {
+			log.Warn("unable to list the nodes, no volume is autoscaled", logger.Err(err))
+		}
+
+		specs := autoscalingSpecs{}
+		for _, node := range nodes.Items {
+			value, ok := node.Annotations[internal.AutoscalingAnnotation]
+			if !ok {
+				continue
+			}
+
+			var report map[string]autoscalingUsage
+			if err := json.Unmarshal([]byte(value), &report); err != nil {
+				log.Warn("unable to decode the volumes to autoscale of a node", logger.Err(err), slog.String("nodeName", node.Name))
+				continue
+			}
+			for volumeID, usage := range report {
+				d.autoscaleVolume(ctx, log.With("volumeID", volumeID, "nodeName", node.Name), node.Name, volumeID, usage, specs)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// autoscaleVolume raises the request of the claim of a filesystem volume the
+// node nodeName reports used above the autoscaling threshold of its
+// LocalStorageClass. The external-resizer and NodeExpandVolume take it from
+// there, as for a request raised by hand.
+//
+// The report of the node is only trusted for the usage: the claim, the class
+// and the free space come from the PersistentVolume, the LVMLogicalVolume and
+// its LVMVolumeGroup, which must be on nodeName.
+//
+// A claim being expanded is left alone until the expansion is over, so a
+// volume grows by one increment at a time however long the expansion takes.
+// The node reports once a minute, so the report read right after an expansion
+// may still be the one from before it: a report for a filesystem clearly
+// smaller than the capacity of the claim is left for the next one.
+// The request is raised with an optimistic lock, so the replicas of the
+// controller do not raise it twice.
+func (d *Driver) autoscaleVolume(ctx context.Context, log logger.Logger, nodeName, volumeID string, usage autoscalingUsage, specs autoscalingSpecs) {
+	pv := &corev1.PersistentVolume{}
+	if err := d.cl.Get(ctx, client.ObjectKey{Name: volumeID}, pv); err != nil {
+		log.Warn("unable to get the PersistentVolume to autoscale", logger.Err(err))
+		return
+	}
+	if pv.Spec.ClaimRef == nil || pv.Spec.StorageClassName == "" {
+		return
+	}
+
+	spec := specs.get(ctx, d.cl, log, pv.Spec.StorageClassName)
+	if !aboveThreshold(spec, usage) {
+		return
+	}
+	usedPercent := usage.Used * 100 / (usage.Used + usage.Available)
+
+	log = log.With("pvcName", pv.Spec.ClaimRef.Name, "pvcNamespace", pv.Spec.ClaimRef.Namespace)
+	target := events.Target{PVCName: pv.Spec.ClaimRef.Name, PVCNamespace: pv.Spec.ClaimRef.Namespace}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := d.cl.Get(ctx, client.ObjectKey{Namespace: pv.Spec.ClaimRef.Namespace, Name: pv.Spec.ClaimRef.Name}, pvc); err != nil {
+		log.Warn("unable to get the PersistentVolumeClaim to autoscale", logger.Err(err))
+		return
+	}
+
+	requested, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if !ok {
+		return
+	}
+	capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+	if !ok || capacity.Cmp(requested) < 0 {
+		log.Debug("the volume is being expanded, not autoscaling it", "requested", requested.String(), "capacity", capacity.String())
+		return
+	}
+	if usage.Size*100 < capacity.Value()*(100-staleUsageMarginPercent) {
+		log.Debug("the usage was reported before the last expansion of the volume, not autoscaling it", "size", usage.Size, "capacity", capacity.String())
+		return
+	}
+
+	if requested.Cmp(spec.MaxSize) >= 0 {
+		d.events.Warning(ctx, target, events.ReasonAutoscalingBlocked, "The volume is used at %d%%, but it has reached the autoscaling maximum of %s", usedPercent, spec.MaxSize.String())
+		return
+	}
+
+	newSize := requested.DeepCopy()
+	newSize.Add(spec.Increment)
+	if newSize.Cmp(spec.MaxSize) > 0 {
+		newSize = spec.MaxSize.DeepCopy()
+	}
+
+	growth := newSize.DeepCopy()
+	growth.Sub(requested)
+	headroom, err := d.volumeHeadroom(ctx, nodeName, volumeID)
+	if err != nil {
+		log.Warn("unable to get the free space for the volume to grow into", logger.Err(err))
+		return
+	}
+	if growth.Cmp(headroom) > 0 {
+		d.events.Warning(ctx, target, events.ReasonAutoscalingBlocked, "The volume is used at %d%%, but expanding it by %s needs more than the %s free in its volume group or thin pool on node %s",
+			usedPercent, growth.String(), headroom.String(), nodeName)
+		return
+	}
+
+	original := pvc.DeepCopy()
+	pvc.Spec.Resources.Requests[corev1.ResourceStorage] = newSize
+	if err := d.cl.Patch(ctx, pvc, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})); err != nil {
+		log.Error("unable to raise the request of the PersistentVolumeClaim", logger.Err(err))
+		return
+	}
+
+	log.Info("autoscaling the volume", "usedPercent", usedPercent, "from", requested.String(), "to", newSize.String())
+	d.events.Normal(ctx, target, events.ReasonVolumeAutoscaled, "The volume is used at %d%%, above the threshold of %d%%: expanding it from %s to %s",
+		usedPercent, spec.ThresholdPercent, requested.String(), newSize.String())
+}
+
+// volumeHeadroom returns the space the volume volumeID can grow by: the free
+// space of its thin pool for a thin volume, that of its VG for a thick one, as
+// its LVMVolumeGroup reports them. The LVMVolumeGroup must be on nodeName.
+func (d *Driver) volumeHeadroom(ctx context.Context, nodeName, volumeID string) (resource.Quantity, error) {
+	llv, err := utils.GetLVMLogicalVolume(ctx, d.cl, volumeID, "")
+	if err != nil {
+		return resource.Quantity{}, err
+	}
+	lvg, err := utils.GetLVMVolumeGroup(ctx, d.cl, llv.Spec.LVMVolumeGroupName)
+	if err != nil {
+		return resource.Quantity{}, err
+	}
+	if lvg.Spec.Local.NodeName != nodeName {
+		return resource.Quantity{}, fmt.Errorf("the LVMVolumeGroup %s of the volume is on node %s", lvg.Name, lvg.Spec.Local.NodeName)
+	}
+
+	if llv.Spec.Thin != nil {
+		return utils.GetLVMThinPoolFreeSpace(*lvg, llv.Spec.Thin.PoolName)
+	}
+	return utils.GetLVMVolumeGroupFreeSpace(*lvg), nil
+}
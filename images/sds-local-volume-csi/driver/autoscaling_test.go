@@ -0,0 +1,159 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	slv "github.com/deckhouse/sds-local-volume/api/v1alpha1"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/events"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-node-configurator/api/v1alpha1"
+)
+
+func newAutoscalingScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, slv.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+func TestAutoscaleVolume(t *testing.T) {
+	const gi = int64(1) << 30
+
+	for _, tc := range []struct {
+		name      string
+		used      int64
+		size      int64
+		requested string
+		capacity  string
+		vgFree    string
+		lvgNode   string
+		want      string
+		event     string
+	}{
+		{name: "below_threshold", used: 7 * gi, capacity: "10Gi", vgFree: "100Gi", want: "10Gi"},
+		{name: "above_threshold", used: 9 * gi, capacity: "10Gi", vgFree: "100Gi", want: "15Gi", event: "Normal VolumeAutoscaled"},
+		{name: "being_expanded", used: 9 * gi, capacity: "8Gi", vgFree: "100Gi", want: "10Gi"},
+		// The node has not reported since the volume grew from 10Gi to 15Gi.
+		{name: "stale_report", used: 9 * gi, requested: "15Gi", capacity: "15Gi", vgFree: "100Gi", want: "15Gi"},
+		{name: "no_headroom", used: 9 * gi, capacity: "10Gi", vgFree: "1Gi", want: "10Gi", event: "Warning AutoscalingBlocked"},
+		// A node only reports the volumes of its own LVMVolumeGroups.
+		{name: "other_node", used: 9 * gi, capacity: "10Gi", vgFree: "100Gi", lvgNode: "node-2", want: "10Gi"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lsc := &slv.LocalStorageClass{
+				ObjectMeta: metav1.ObjectMeta{Name: "fast"},
+				Spec: slv.LocalStorageClassSpec{Autoscaling: &slv.LocalStorageClassAutoscalingSpec{
+					ThresholdPercent: 80,
+					Increment:        resource.MustParse("5Gi"),
+					MaxSize:          resource.MustParse("100Gi"),
+				}},
+			}
+			requested, size := tc.requested, tc.size
+			if requested == "" {
+				requested = "10Gi"
+			}
+			if size == 0 {
+				size = 10 * gi
+			}
+			pvc := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "db"},
+				Spec: corev1.PersistentVolumeClaimSpec{Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(requested)},
+				}},
+				Status: corev1.PersistentVolumeClaimStatus{
+					Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(tc.capacity)},
+				},
+			}
+			pv := &corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+				Spec: corev1.PersistentVolumeSpec{
+					ClaimRef:         &corev1.ObjectReference{Name: "data", Namespace: "db"},
+					StorageClassName: "fast",
+				},
+			}
+			llv := &v1alpha1.LVMLogicalVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+				Spec:       v1alpha1.LVMLogicalVolumeSpec{LVMVolumeGroupName: "lvg-1"},
+			}
+			lvgNode := tc.lvgNode
+			if lvgNode == "" {
+				lvgNode = "node-1"
+			}
+			lvg := &v1alpha1.LVMVolumeGroup{ObjectMeta: metav1.ObjectMeta{Name: "lvg-1"}}
+			lvg.Spec.Local.NodeName = lvgNode
+			lvg.Status.VGSize = resource.MustParse("200Gi")
+			lvg.Status.AllocatedSize = lvg.Status.VGSize.DeepCopy()
+			lvg.Status.AllocatedSize.Sub(resource.MustParse(tc.vgFree))
+
+			cl := fake.NewClientBuilder().WithScheme(newAutoscalingScheme(t)).WithObjects(lsc, pvc, pv, llv, lvg).Build()
+			recorder := record.NewFakeRecorder(10)
+			d := &Driver{
+				cl:     cl,
+				events: events.NewRecorderFor(cl, recorder, logger.NewNop()),
+			}
+
+			usage := autoscalingUsage{Size: size, Used: tc.used, Available: size - tc.used}
+			d.autoscaleVolume(context.Background(), logger.NewNop(), "node-1", "pvc-1", usage, autoscalingSpecs{})
+
+			got := &corev1.PersistentVolumeClaim{}
+			require.NoError(t, cl.Get(context.Background(), client.ObjectKeyFromObject(pvc), got))
+			want := resource.MustParse(tc.want)
+			assert.Zero(t, want.Cmp(got.Spec.Resources.Requests[corev1.ResourceStorage]), "request %s, want %s", got.Spec.Resources.Requests.Storage(), tc.want)
+
+			select {
+			case e := <-recorder.Events:
+				assert.Contains(t, e, tc.event)
+				assert.NotEmpty(t, tc.event, "unexpected event %q", e)
+			default:
+				assert.Empty(t, tc.event, "no event")
+			}
+		})
+	}
+}
+
+// The node plugin reports the volumes to autoscale in an annotation of its
+// node, and removes it once there are none.
+func TestReportAutoscaling(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Annotations: map[string]string{"other": "kept"}}}
+	cl := fake.NewClientBuilder().WithScheme(newAutoscalingScheme(t)).WithObjects(node).Build()
+	d := &Driver{cl: cl, hostID: "node-1"}
+
+	d.reportAutoscaling(context.Background(), logger.NewNop(), map[string]autoscalingUsage{"pvc-1": {Size: 10, Used: 9, Available: 1}})
+	got := &corev1.Node{}
+	require.NoError(t, cl.Get(context.Background(), client.ObjectKeyFromObject(node), got))
+	assert.Equal(t, map[string]string{"other": "kept", internal.AutoscalingAnnotation: `{"pvc-1":{"size":10,"used":9,"available":1}}`}, got.Annotations)
+
+	d.reportAutoscaling(context.Background(), logger.NewNop(), map[string]autoscalingUsage{})
+	require.NoError(t, cl.Get(context.Background(), client.ObjectKeyFromObject(node), got))
+	assert.Equal(t, map[string]string{"other": "kept"}, got.Annotations)
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+)
+
+const (
+	// xfsProbeDir is where in the plugin directory the xfs features are
+	// probed.
+	xfsProbeDir = "xfs-probe"
+	// nodeLabelsTimeout bounds the labeling of the node with what it supports.
+	nodeLabelsTimeout = 10 * time.Second
+)
+
+// probeNodeCapabilities probes the xfs features the node formats and mounts,
+// which the xfs volumes are formatted with, and labels the node with them.
+// pluginDir is the plugin directory on the host.
+//
+// A probe that fails before mkfs.xfs has told its features falls back to the
+// kernel version: all the features are turned off on a kernel older than the
+// ones mounting them, and left to the defaults of mkfs.xfs otherwise. If even
+// the kernel version is unknown, the xfs volumes are not staged at all. The
+// labels always tell what the xfs volumes are formatted with, so those of an
+// earlier probe do not outlive a fallback; the node has none when no xfs
+// volume is staged.
+func (d *Driver) probeNodeCapabilities(ctx context.Context, pluginDir string) {
+	log := d.log.Named("probeNodeCapabilities")
+
+	support, err := d.storeManager.ProbeXFSFeatures(filepath.Join(pluginDir, xfsProbeDir))
+	switch {
+	case err != nil && support != nil:
+		log.Warn("unable to probe the xfs features, the xfs volumes are formatted without the ones mkfs.xfs knows", logger.Err(err))
+	case err != nil:
+		legacy, legacyErr := needLegacyXFSSupport()
+		if legacyErr != nil {
+			log.Error("unable to probe the xfs features nor to tell the kernel version, no xfs volume is staged", logger.Err(errors.Join(err, legacyErr)))
+			break
+		}
+		support = legacyXFSSupport(legacy)
+		log.Warn("unable to probe the xfs features, falling back to the kernel version", logger.Err(err), slog.Bool("legacyKernel", legacy))
+	default:
+		log.Info("probed the xfs features", "features", fmt.Sprintf("%+v", support))
+	}
+	d.xfsSupport = support
+
+	if err := d.labelNode(ctx, support); err != nil {
+		log.Warn("unable to label the node with the xfs features", logger.Err(err))
+	}
+}
+
+// labelNode sets the internal.XFSFeatureLabelPrefix labels of the node, true
+// for a feature the node both formats and mounts, and removes them when
+// support is nil. The labels are merged into the node without reading it, so
+// the node plugin needs no more than to patch nodes.
+func (d *Driver) labelNode(ctx context.Context, support map[string]utils.XFSSupport) error {
+	ctx, cancel := context.WithTimeout(ctx, nodeLabelsTimeout)
+	defer cancel()
+
+	labels := make(map[string]any, len(utils.XFSFeatures))
+	for _, feature := range utils.XFSFeatures {
+		labels[internal.XFSFeatureLabelPrefix+feature.Name] = nil
+	}
+	for name, feature := range support {
+		labels[internal.XFSFeatureLabelPrefix+name] = strconv.FormatBool(feature.Mkfs && feature.Kernel)
+	}
+	patch, err := json.Marshal(map[string]any{"metadata": map[string]any{"labels": labels}})
+	if err != nil {
+		return err
+	}
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: d.hostID}}
+	return d.cl.Patch(ctx, node, client.RawPatch(types.MergePatchType, patch))
+}
+
+// legacyXFSSupport is what is assumed of a node the xfs features could not be
+// probed on: mkfs.xfs of the plugin image knows them all, and the kernel
+// mounts them unless it is legacy.
+func legacyXFSSupport(legacy bool) map[string]utils.XFSSupport {
+	support := make(map[string]utils.XFSSupport, len(utils.XFSFeatures))
+	for _, feature := range utils.XFSFeatures {
+		support[feature.Name] = utils.XFSSupport{Mkfs: true, Kernel: !legacy}
+	}
+	return support
+}
+
+// needLegacyXFSSupport tells whether the kernel of the node is 5.15 or older,
+// which mounts none of utils.XFSFeatures.
+func needLegacyXFSSupport() (bool, error) {
+	var uname syscall.Utsname
+	if err := syscall.Uname(&uname); err != nil {
+		return false, fmt.Errorf("unable to Uname kernel version: %w", err)
+	}
+
+	fullVersion := readCString(uname.Release[:]) // similar to: "6.8.0-44-generic"
+
+	parts := strings.SplitN(fullVersion, ".", 3)
+	if len(parts) < 3 {
+		return false, fmt.Errorf("unexpected kernel version: %s", fullVersion)
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false, fmt.Errorf("unexpected kernel version (major part): %s", fullVersion)
+	}
+
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false, fmt.Errorf("unexpected kernel version (minor part): %s", fullVersion)
+	}
+
+	return major < 5 || major == 5 && minor <= 15, nil
+}
+
+func readCString(arr []int8) string {
+	b := make([]byte, 0, len(arr))
+	for _, v := range arr {
+		if v == 0x00 {
+			break
+		}
+		b = append(b, byte(v))
+	}
+	return string(b)
+}
+
+// xfsFormatOptions returns the mkfs.xfs options turning off the features the
+// mkfs.xfs of the node turns on and its kernel cannot mount. The features the
+// mkfs options of the class set are left to them, and turning on one the
+// kernel cannot mount is refused, as is formatting on a node nothing is known
+// of.
+func xfsFormatOptions(support map[string]utils.XFSSupport, classArgs []string) ([]string, error) {
+	if support == nil {
+		return nil, errors.New("the xfs features the node formats and mounts are unknown, see the log of the node plugin")
+	}
+
+	set := map[string]bool{}
+	for i := 0; i+1 < len(classArgs); i++ {
+		for _, suboption := range strings.Split(classArgs[i+1], ",") {
+			name, value, _ := strings.Cut(suboption, "=")
+			for _, feature := range utils.XFSFeatures {
+				if feature.Option != classArgs[i] || feature.Name != name {
+					continue
+				}
+				if value != "0" && !support[name].Kernel {
+					return nil, fmt.Errorf("the kernel of the node cannot mount xfs with %s", name)
+				}
+				set[name] = true
+			}
+		}
+	}
+
+	off := map[string]bool{}
+	for name, feature := range support {
+		if feature.Mkfs && !feature.Kernel && !set[name] {
+			off[name] = false
+		}
+	}
+
+	return utils.XFSFeatureArgs(off), nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+)
+
+func TestXFSFormatOptions(t *testing.T) {
+	// A recent mkfs.xfs on a kernel that mounts neither reflink nor nrext64.
+	support := map[string]utils.XFSSupport{
+		"bigtime":    {Mkfs: true, Kernel: true},
+		"inobtcount": {Mkfs: true, Kernel: true},
+		"reflink":    {Mkfs: true},
+		"nrext64":    {Mkfs: true},
+	}
+
+	args, err := xfsFormatOptions(support, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-m", "reflink=0", "-i", "nrext64=0"}, args)
+
+	// The class turns reflink off itself.
+	args, err = xfsFormatOptions(support, []string{"-m", "reflink=0,crc=1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-i", "nrext64=0"}, args)
+
+	_, err = xfsFormatOptions(support, []string{"-i", "nrext64=1"})
+	assert.Error(t, err)
+
+	// Nothing is known of a node neither the probe nor the kernel version
+	// worked on.
+	_, err = xfsFormatOptions(nil, nil)
+	assert.Error(t, err)
+
+	// A legacy kernel the probe failed on gets all the features off.
+	args, err = xfsFormatOptions(legacyXFSSupport(true), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-m", "bigtime=0,inobtcount=0,reflink=0", "-i", "nrext64=0"}, args)
+}
+
+// The labels of an earlier probe are replaced, and removed when no xfs volume
+// is staged.
+func TestLabelNode(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{
+		"zone": "a",
+		"local.csi.storage.deckhouse.io/xfs-nrext64": "true",
+	}}}
+	cl := fake.NewClientBuilder().WithObjects(node).Build()
+	d := &Driver{hostID: "node-1", cl: cl}
+
+	require.NoError(t, d.labelNode(context.Background(), map[string]utils.XFSSupport{
+		"bigtime": {Mkfs: true, Kernel: true},
+		"reflink": {Mkfs: true},
+	}))
+
+	got := &corev1.Node{}
+	require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Name: "node-1"}, got))
+	assert.Equal(t, map[string]string{
+		"zone": "a",
+		"local.csi.storage.deckhouse.io/xfs-bigtime": "true",
+		"local.csi.storage.deckhouse.io/xfs-reflink": "false",
+	}, got.Labels)
+
+	require.NoError(t, d.labelNode(context.Background(), nil))
+	require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Name: "node-1"}, got))
+	assert.Equal(t, map[string]string{"zone": "a"}, got.Labels)
+}
@@ -21,18 +21,28 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
-	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/encryption"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/events"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/qos"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/tracing"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
 	"github.com/deckhouse/sds-local-volume/lib/go/common/pkg/feature"
+	"github.com/deckhouse/sds-local-volume/lib/go/common/pkg/lvname"
 	"github.com/deckhouse/sds-node-configurator/api/v1alpha1"
 )
 
@@ -98,8 +108,131 @@ func resolveCapacityBytes(llv *v1alpha1.LVMLogicalVolume, fallback resource.Quan
 	return fallback.Value(), false
 }
 
+// resolveLVName returns the name of the LV of a new volume on the node: the
+// LocalStorageClass template rendered for the volume, or the volume ID if the
+// class has none.
+func resolveLVName(params map[string]string, volumeID string) (string, error) {
+	text := params[internal.LVNameTemplateKey]
+	if text == "" {
+		return volumeID, nil
+	}
+
+	tmpl, err := lvname.Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid LV name template %q: %w", text, err)
+	}
+
+	return tmpl.Render(lvname.NewValues(params[internal.PVCNamespaceKey], params[internal.PVCNameKey], volumeID))
+}
+
+// mutableParameterKeys are the parameters a VolumeAttributesClass may set.
+// Those of the claim override the StorageClass ones in CreateVolume and are
+// changed later with ControllerModifyVolume, which keeps them as annotations of
+// the LVMLogicalVolume under the same keys.
+var mutableParameterKeys = append([]string{
+	LVMVolumeCleanupParamKey,
+	internal.LVMVThickContiguousParamKey,
+	internal.LVTagsKey,
+}, qos.Keys...)
+
+// volumeCleanupMethods are the values of LVMVolumeCleanupParamKey, see the
+// volumeCleanup of the LocalStorageClass. An empty value turns cleanup off.
+var volumeCleanupMethods = []string{"RandomFillSinglePass", "RandomFillThreePass", "Discard"}
+
+// withMutableParameters returns params with mutableParams, the parameters of
+// the VolumeAttributesClass of the claim, replacing their own.
+func withMutableParameters(params, mutableParams map[string]string) (map[string]string, error) {
+	for key := range mutableParams {
+		if !slices.Contains(mutableParameterKeys, key) {
+			return nil, fmt.Errorf("unsupported VolumeAttributesClass parameter %q, the supported ones are %s", key, strings.Join(mutableParameterKeys, ", "))
+		}
+	}
+
+	merged := maps.Clone(params)
+	if merged == nil {
+		merged = make(map[string]string, len(mutableParams))
+	}
+	maps.Copy(merged, mutableParams)
+
+	return merged, nil
+}
+
+// validateMutableParameters checks the values of the mutable parameters of a
+// volume of the given LVM type.
+func validateMutableParameters(mutableParams map[string]string, lvmType string) error {
+	if _, err := (qos.Limits{}).With(mutableParams); err != nil {
+		return err
+	}
+
+	if value, ok := mutableParams[LVMVolumeCleanupParamKey]; ok && value != "" {
+		if !slices.Contains(volumeCleanupMethods, value) {
+			return fmt.Errorf("invalid %s %q, the supported ones are %s", LVMVolumeCleanupParamKey, value, strings.Join(volumeCleanupMethods, ", "))
+		}
+		if !feature.VolumeCleanupEnabled() {
+			return errors.New("volume cleanup is not supported in your edition")
+		}
+	}
+
+	if value, ok := mutableParams[internal.LVMVThickContiguousParamKey]; ok {
+		if value != "true" && value != "false" {
+			return fmt.Errorf("invalid %s %q, must be true or false", internal.LVMVThickContiguousParamKey, value)
+		}
+		if lvmType != internal.LVMTypeThick {
+			return fmt.Errorf("%s applies to %s volumes only", internal.LVMVThickContiguousParamKey, internal.LVMTypeThick)
+		}
+	}
+
+	if value, ok := mutableParams[internal.LVTagsKey]; ok {
+		if _, err := utils.ParseLVTags(value); err != nil {
+			return fmt.Errorf("invalid %s: %w", internal.LVTagsKey, err)
+		}
+	}
+
+	return nil
+}
+
+// createdParameters returns the parameters a volume was created with, which
+// the volume context of its PersistentVolume holds. A volume without a
+// PersistentVolume has none.
+func (d *Driver) createdParameters(ctx context.Context, volumeID string) (map[string]string, error) {
+	pv := &corev1.PersistentVolume{}
+	if err := d.cl.Get(ctx, client.ObjectKey{Name: volumeID}, pv); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if pv.Spec.CSI == nil {
+		return nil, nil
+	}
+
+	return pv.Spec.CSI.VolumeAttributes, nil
+}
+
+// ensureVolumeKey creates the Secret holding the passphrase of a volume of a
+// class with per-volume keys. A volume made from a snapshot or cloned from
+// another volume is a copy of LUKS data, so it gets the passphrase of its
+// source; a new volume gets a new one.
+func (d *Driver) ensureVolumeKey(ctx context.Context, log logger.Logger, volumeID string, source *v1alpha1.LVMLogicalVolumeSource) error {
+	if source == nil {
+		if err := encryption.GenerateSecret(ctx, d.cl, volumeID); err != nil {
+			log.Error("unable to generate the volume passphrase", logger.Err(err))
+			return status.Error(codes.Internal, err.Error())
+		}
+		return nil
+	}
+
+	copied, err := encryption.CopySecret(ctx, d.cl, volumeID, source.Name)
+	if err != nil {
+		log.Error("unable to copy the passphrase of the source", logger.Err(err), slog.String("sourceName", source.Name))
+		return status.Error(codes.Internal, err.Error())
+	}
+	if !copied {
+		return status.Errorf(codes.FailedPrecondition, "the source %s %s has no passphrase Secret: it is not encrypted, or its Secret has been deleted", source.Kind, source.Name)
+	}
+
+	return nil
+}
+
 func (d *Driver) CreateVolume(ctx context.Context, request *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
-	traceID := uuid.New().String()
+	traceID := tracing.TraceID(ctx)
 
 	// Named + With replace the "[CreateVolume][traceID:...][volumeID:...]" prefix
 	// that used to be interpolated into every message of this RPC.
@@ -123,36 +256,64 @@ func (d *Driver) CreateVolume(ctx context.Context, request *csi.CreateVolumeRequ
 	// logger here rather than above.
 	log = log.With("volumeID", volumeID)
 
-	BindingMode := request.Parameters[internal.BindingModeKey]
+	// The lock lets the drain on shutdown tell a half-created LVMLogicalVolume.
+	if !d.inFlight.InsertOperation(volumeID, "CreateVolume", traceID) {
+		return nil, d.inFlightAbortedErr(volumeID)
+	}
+	defer d.inFlight.Delete(volumeID)
+
+	// A VolumeAttributesClass of the claim overrides the mutable parameters of
+	// the StorageClass. Everything below reads the merged parameters.
+	params, err := withMutableParameters(request.Parameters, request.MutableParameters)
+	if err == nil {
+		err = validateMutableParameters(request.MutableParameters, request.Parameters[internal.LvmTypeKey])
+	}
+	if err != nil {
+		log.Error("invalid VolumeAttributesClass parameters", logger.Err(err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	eventTarget := events.TargetFromParameters(params)
+
+	BindingMode := params[internal.BindingModeKey]
 	log.Info("storage class binding mode", "bindingMode", BindingMode)
 
-	LvmType := request.Parameters[internal.LvmTypeKey]
+	LvmType := params[internal.LvmTypeKey]
 	log.Info("storage class LVM type", "lvmType", LvmType)
 
-	if len(request.Parameters[internal.LVMVolumeGroupKey]) == 0 {
+	if len(params[internal.LVMVolumeGroupKey]) == 0 {
 		err := errors.New("no LVMVolumeGroups specified in a storage class's parameters")
 		log.Error("no LVMVolumeGroups were found for the request", logger.Err(err), slog.String("request", request.String()))
 		return nil, status.Errorf(codes.InvalidArgument, "no LVMVolumeGroups specified in a storage class's parameters")
 	}
 
-	storageClassLVGs, storageClassLVGParametersMap, err := utils.GetStorageClassLVGsAndParameters(ctx, d.cl, log, request.Parameters[internal.LVMVolumeGroupKey])
+	storageClassLVGs, storageClassLVGParametersMap, err := utils.GetStorageClassLVGsAndParameters(ctx, d.cl, log, params[internal.LVMVolumeGroupKey])
 	if err != nil {
 		log.Error("unable to get the storage class LVMVolumeGroups", logger.Err(err))
 		return nil, status.Errorf(codes.Internal, "error during GetStorageClassLVGs")
 	}
 
-	contiguous := utils.IsContiguous(request, LvmType)
+	contiguous := utils.IsContiguous(params, LvmType)
 	log.Info("resolved contiguous", "contiguous", contiguous)
 
-	// TODO: Consider refactoring the naming strategy for llvName and lvName.
-	// Currently, we use the same name for llvName (the name of the LVMLogicalVolume resource in Kubernetes)
-	// and lvName (the name of the LV in LVM on the node) because the PV name is unique within the cluster,
-	// preventing name collisions. This approach simplifies matching between nodes and Kubernetes by maintaining
-	// the same name in both contexts. Future consideration should be given to optimizing this logic to enhance
-	// code readability and maintainability.
+	// llvName (the LVMLogicalVolume resource) is the PV name: it is unique within
+	// the cluster and makes the two easy to match. lvName (the LV on the node) is
+	// the PV name too unless the LocalStorageClass sets a template for it. What
+	// the volume belongs to is recorded in the labels and annotations of the
+	// LVMLogicalVolume, see utils.LLVMetadataFromParameters.
 	llvName := volumeID
-	lvName := volumeID
-	log.Info("resolved LVMLogicalVolume name", "llvName", llvName)
+	lvName, err := resolveLVName(params, volumeID)
+	if err != nil {
+		log.Error("unable to resolve the LV name", logger.Err(err))
+		return nil, status.Errorf(codes.InvalidArgument, "unable to resolve the LV name: %s", err.Error())
+	}
+	log.Info("resolved LVMLogicalVolume and LV names", "llvName", llvName, "lvName", lvName)
+
+	ioLimits, err := qos.Limits{}.With(params)
+	if err != nil {
+		log.Error("unable to resolve the IO limits", logger.Err(err))
+		return nil, status.Errorf(codes.InvalidArgument, "unable to resolve the IO limits: %s", err.Error())
+	}
 
 	llvSize := resource.NewQuantity(request.CapacityRange.GetRequiredBytes(), resource.BinarySI)
 	log.Info("resolved LVMLogicalVolume size", "llvSize", llvSize.String())
@@ -214,6 +375,11 @@ func (d *Driver) CreateVolume(ctx context.Context, request *csi.CreateVolumeRequ
 				}
 			}
 
+			// A read-only claim gets the snapshot itself rather than a clone.
+			if readOnlyAccess(request.VolumeCapabilities) {
+				return d.snapshotVolume(ctx, log, request, params, sourceVol, selectedLVG, *llvSize)
+			}
+
 			preferredNode = sourceVol.Status.NodeName
 		case *csi.VolumeContentSource_Volume:
 			sourceVolume.Kind = sourceVolumeKindVolume
@@ -292,6 +458,7 @@ func (d *Driver) CreateVolume(ctx context.Context, request *csi.CreateVolumeRequ
 		log.Info("selected an LVMVolumeGroup", "lvg", fmt.Sprintf("%+v", selectedLVG))
 		if err != nil {
 			log.Error("unable to select an LVMVolumeGroup", logger.Err(err), slog.String("preferredNode", preferredNode))
+			d.events.Warning(ctx, eventTarget, events.ReasonLVGNotFound, "No LVMVolumeGroup of the storage class is available on node %q", preferredNode)
 			return nil, status.Errorf(codes.Internal, "error during SelectLVG")
 		}
 
@@ -307,11 +474,12 @@ func (d *Driver) CreateVolume(ctx context.Context, request *csi.CreateVolumeRequ
 		*llvSize = alignedLlvSize
 
 		if !fitsFreeSpace(BindingMode, LvmType, *llvSize, maxFreeSpace) {
+			d.events.Warning(ctx, eventTarget, events.ReasonInsufficientSpace, "Requested size %s is greater than the free space %s of LVMVolumeGroup %s", llvSize.String(), maxFreeSpace.String(), selectedLVG.Name)
 			return nil, status.Errorf(codes.Internal, "requested size: %s is greater than free space: %s", llvSize.String(), maxFreeSpace.String())
 		}
 	}
 
-	volumeCleanup := request.Parameters[LVMVolumeCleanupParamKey]
+	volumeCleanup := params[LVMVolumeCleanupParamKey]
 	if !feature.VolumeCleanupEnabled() && volumeCleanup != "" {
 		return nil, errors.New("volume cleanup is not supported in your edition")
 	}
@@ -330,8 +498,35 @@ func (d *Driver) CreateVolume(ctx context.Context, request *csi.CreateVolumeRequ
 
 	log.Info("built the LVMLogicalVolume spec", "spec", fmt.Sprintf("%+v", llvSpec))
 
+	if params[encryption.ModeKey] == encryption.ModePerVolumeKey {
+		if err := d.ensureVolumeKey(ctx, log, volumeID, sourceVolume); err != nil {
+			return nil, err
+		}
+	}
+
 	log.Trace("creating the LVMLogicalVolume")
-	_, err = utils.CreateLVMLogicalVolume(ctx, d.cl, log, llvName, llvSpec)
+	// The trace context goes onto the resource itself, so whoever looks at a
+	// stuck LVMLogicalVolume can find the CreateVolume call that asked for it.
+	createCtx, span := tracing.Start(ctx, "CreateLVMLogicalVolume", attribute.String("llv.name", llvName), attribute.String("lvg.name", selectedLVG.Name))
+	llvLabels, llvAnnotations := utils.LLVMetadataFromParameters(params)
+	if traceAnnotations := tracing.Annotations(createCtx); traceAnnotations != nil {
+		if llvAnnotations == nil {
+			llvAnnotations = make(map[string]string, len(traceAnnotations))
+		}
+		maps.Copy(llvAnnotations, traceAnnotations)
+	}
+	if len(request.MutableParameters) > 0 {
+		if llvAnnotations == nil {
+			llvAnnotations = make(map[string]string, len(request.MutableParameters))
+		}
+		maps.Copy(llvAnnotations, request.MutableParameters)
+	}
+	_, err = utils.CreateLVMLogicalVolume(createCtx, d.cl, log, llvName, llvLabels, llvAnnotations, llvSpec)
+	if kerrors.IsAlreadyExists(err) {
+		tracing.End(span, nil)
+	} else {
+		tracing.End(span, err)
+	}
 	if err != nil {
 		if kerrors.IsAlreadyExists(err) {
 			log.Info("the LVMLogicalVolume already exists, skipping creation", "llvName", llvName)
@@ -344,11 +539,23 @@ func (d *Driver) CreateVolume(ctx context.Context, request *csi.CreateVolumeRequ
 
 	log.Trace("waiting for the LVMLogicalVolume status")
 
-	createdLLV, attemptCounter, err := utils.WaitForStatusUpdate(ctx, d.cl, log, request.Name, "", *llvSize, utils.SafeExtentSize(selectedLVG.Status.ExtentSize))
+	waitCtx, span := tracing.Start(ctx, "WaitForLVMLogicalVolume", attribute.String("llv.name", llvName))
+	createdLLV, attemptCounter, err := utils.WaitForStatusUpdate(waitCtx, d.cl, log, request.Name, "", *llvSize, utils.SafeExtentSize(selectedLVG.Status.ExtentSize))
+	span.SetAttributes(attribute.Int("attempts", attemptCounter))
+	tracing.End(span, err)
 	if err != nil {
 		log.Error("the LVMLogicalVolume did not become ready, deleting it", logger.Err(err), slog.String("llvName", request.Name))
 
-		deleteErr := utils.DeleteLVMLogicalVolume(ctx, d.cl, log, request.Name, volumeCleanup)
+		// A stale LVMVolumeGroup is the usual reason for the agent not picking the
+		// volume up; anything else is the agent's own failure and its reason is in
+		// the returned error.
+		if selectedLVG.Status.Phase != internal.LVGStatusReady {
+			d.events.Warning(ctx, eventTarget, events.ReasonLVGNotReady, "LVMVolumeGroup %s is in the %q phase, the volume could not be created: %v", selectedLVG.Name, selectedLVG.Status.Phase, err)
+		}
+
+		deleteCtx, span := tracing.Start(ctx, "DeleteLVMLogicalVolume", attribute.String("llv.name", llvName))
+		deleteErr := utils.DeleteLVMLogicalVolume(deleteCtx, d.cl, log, request.Name, volumeCleanup)
+		tracing.End(span, deleteErr)
 		if deleteErr != nil {
 			log.Error("unable to delete the LVMLogicalVolume after a failed wait", logger.Err(deleteErr), slog.String("llvName", request.Name))
 		}
@@ -363,13 +570,26 @@ func (d *Driver) CreateVolume(ctx context.Context, request *csi.CreateVolumeRequ
 		log.Warn("the LVMLogicalVolume has no status after a successful wait, reporting the aligned requested size", "llvName", request.Name, "alignedSize", llvSize.String())
 	}
 
-	volumeCtx := make(map[string]string, len(request.Parameters))
-	for k, v := range request.Parameters {
+	volumeCtx := make(map[string]string, len(params))
+	for k, v := range params {
 		volumeCtx[k] = v
 	}
 
 	volumeCtx[internal.SubPath] = request.Name
 	volumeCtx[internal.VGNameKey] = selectedLVG.Spec.ActualVGNameOnTheNode
+	// The node resolves the device through the UUID, the name above is its
+	// fallback. Both are captured once and never updated.
+	if selectedLVG.Status.VGUuid != "" {
+		volumeCtx[internal.VGUUIDKey] = selectedLVG.Status.VGUuid
+	}
+	volumeCtx[internal.LVNameKey] = lvName
+	// The node applies the limits it finds in the volume context, which are the
+	// effective ones rather than those of the class, unless the annotations of
+	// the LVMLogicalVolume override them since.
+	for _, key := range qos.Keys {
+		delete(volumeCtx, key)
+	}
+	maps.Copy(volumeCtx, ioLimits.Parameters())
 	if llvSpec.Type == internal.LVMTypeThin {
 		volumeCtx[internal.ThinPoolNameKey] = llvSpec.Thin.PoolName
 	} else {
@@ -394,7 +614,7 @@ func (d *Driver) CreateVolume(ctx context.Context, request *csi.CreateVolumeRequ
 }
 
 func (d *Driver) DeleteVolume(ctx context.Context, request *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
-	traceID := uuid.New().String()
+	traceID := tracing.TraceID(ctx)
 	log := d.log.Named("DeleteVolume").With("traceID", traceID, "volumeID", request.VolumeId)
 
 	log.Info("start")
@@ -402,7 +622,31 @@ func (d *Driver) DeleteVolume(ctx context.Context, request *csi.DeleteVolumeRequ
 		return nil, status.Error(codes.InvalidArgument, "Volume ID cannot be empty")
 	}
 
+	if !d.inFlight.InsertOperation(request.VolumeId, "DeleteVolume", traceID) {
+		return nil, d.inFlightAbortedErr(request.VolumeId)
+	}
+	defer d.inFlight.Delete(request.VolumeId)
+
+	// The snapshot of a read-only snapshot volume belongs to its
+	// VolumeSnapshot and outlives the volume.
+	snapshotVolume, err := d.isSnapshotVolume(ctx, request.VolumeId)
+	if err != nil {
+		log.Error("unable to get the PersistentVolume", logger.Err(err))
+		return nil, status.Errorf(codes.Internal, "error getting PersistentVolume %s: %s", request.VolumeId, err.Error())
+	}
+	if snapshotVolume {
+		log.Info("the volume is a read-only snapshot, leaving the snapshot alone")
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+
 	volumeCleanup := func() string {
+		// A method set with ControllerModifyVolume wins over the class.
+		if llv, err := utils.GetLVMLogicalVolume(ctx, d.cl, request.VolumeId, ""); err == nil {
+			if method, ok := llv.Annotations[LVMVolumeCleanupParamKey]; ok {
+				return method
+			}
+		}
+
 		localStorageClass, err := utils.GetLSCBeforeLLVDelete(ctx, d.cl, log, request.VolumeId)
 		if err == nil && localStorageClass != nil && localStorageClass.Spec.LVM != nil {
 			return localStorageClass.Spec.LVM.VolumeCleanup
@@ -414,11 +658,20 @@ func (d *Driver) DeleteVolume(ctx context.Context, request *csi.DeleteVolumeRequ
 		return nil, errors.New("volumeCleanup is not supported in your edition")
 	}
 
-	err := utils.DeleteLVMLogicalVolume(ctx, d.cl, log, request.VolumeId, volumeCleanup)
+	deleteCtx, span := tracing.Start(ctx, "DeleteLVMLogicalVolume", attribute.String("llv.name", request.VolumeId))
+	err = utils.DeleteLVMLogicalVolume(deleteCtx, d.cl, log, request.VolumeId, volumeCleanup)
+	tracing.End(span, err)
 	if err != nil {
 		log.Error("unable to delete the LVMLogicalVolume", logger.Err(err))
 		return nil, err
 	}
+
+	// Only volumes of a class with per-volume keys have a Secret, a missing
+	// one is fine. It goes after the LV, which is unreadable without it.
+	if err := encryption.DeleteSecret(ctx, d.cl, request.VolumeId); err != nil {
+		log.Error("unable to delete the Secret of the volume passphrase", logger.Err(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 	log.Info("volume deleted successfully")
 	return &csi.DeleteVolumeResponse{}, nil
 }
@@ -470,6 +723,7 @@ func (d *Driver) ControllerGetCapabilities(_ context.Context, _ *csi.ControllerG
 		csi.ControllerServiceCapability_RPC_GET_CAPACITY,
 		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
 		csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
+		csi.ControllerServiceCapability_RPC_MODIFY_VOLUME,
 	}
 
 	if feature.SnapshotsEnabled() {
@@ -494,7 +748,7 @@ func (d *Driver) ControllerGetCapabilities(_ context.Context, _ *csi.ControllerG
 }
 
 func (d *Driver) ControllerExpandVolume(ctx context.Context, request *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
-	traceID := uuid.New().String()
+	traceID := tracing.TraceID(ctx)
 
 	log := d.log.Named("ControllerExpandVolume").With("traceID", traceID, "volumeID", request.GetVolumeId())
 
@@ -505,6 +759,11 @@ func (d *Driver) ControllerExpandVolume(ctx context.Context, request *csi.Contro
 		return nil, status.Error(codes.InvalidArgument, "Volume id cannot be empty")
 	}
 
+	if !d.inFlight.InsertOperation(volumeID, "ControllerExpandVolume", traceID) {
+		return nil, d.inFlightAbortedErr(volumeID)
+	}
+	defer d.inFlight.Delete(volumeID)
+
 	llv, err := utils.GetLVMLogicalVolume(ctx, d.cl, volumeID, "")
 	if err != nil {
 		log.Error("unable to get the LVMLogicalVolume", logger.Err(err))
@@ -538,9 +797,9 @@ func (d *Driver) ControllerExpandVolume(ctx context.Context, request *csi.Contro
 
 	if llv.Spec.Type == internal.LVMTypeThick {
 		lvgFreeSpace := utils.GetLVMVolumeGroupFreeSpace(*lvg)
-
 		if lvgFreeSpace.Value() < (alignedRequestCapacity.Value() - llv.Status.ActualSize.Value()) {
 			log.Error("the requested size exceeds the free space of the LVMVolumeGroup", slog.String("alignedRequestSize", alignedRequestCapacity.String()), slog.String("lvgFreeSpace", lvgFreeSpace.String()))
+			d.events.Warning(ctx, events.Target{PVName: volumeID}, events.ReasonInsufficientSpace, "Requested size %s does not fit into the free space %s of LVMVolumeGroup %s", alignedRequestCapacity.String(), lvgFreeSpace.String(), lvg.Name)
 			return nil, status.Errorf(codes.Internal, "requested size: %s is greater than the capacity of the LVMVolumeGroup: %s", alignedRequestCapacity.String(), lvgFreeSpace.String())
 		}
 	}
@@ -552,7 +811,10 @@ func (d *Driver) ControllerExpandVolume(ctx context.Context, request *csi.Contro
 		return nil, status.Errorf(codes.Internal, "error updating LVMLogicalVolume: %v", err)
 	}
 
-	updatedLLV, attemptCounter, err := utils.WaitForStatusUpdate(ctx, d.cl, log, llv.Name, llv.Namespace, *requestCapacity, utils.SafeExtentSize(lvg.Status.ExtentSize))
+	waitCtx, span := tracing.Start(ctx, "WaitForLVMLogicalVolume", attribute.String("llv.name", llv.Name))
+	updatedLLV, attemptCounter, err := utils.WaitForStatusUpdate(waitCtx, d.cl, log, llv.Name, llv.Namespace, *requestCapacity, utils.SafeExtentSize(lvg.Status.ExtentSize))
+	span.SetAttributes(attribute.Int("attempts", attemptCounter))
+	tracing.End(span, err)
 	if err != nil {
 		log.Error("the resized LVMLogicalVolume did not become ready", logger.Err(err))
 		return nil, err
@@ -577,7 +839,91 @@ func (d *Driver) ControllerGetVolume(_ context.Context, _ *csi.ControllerGetVolu
 	return &csi.ControllerGetVolumeResponse{}, nil
 }
 
-func (d *Driver) ControllerModifyVolume(_ context.Context, _ *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
-	d.log.Named("ControllerModifyVolume").Info("called")
+// ControllerModifyVolume applies the parameters of the VolumeAttributesClass a
+// claim was switched to. The external-resizer calls it and moves the claim to
+// the new class once it succeeds.
+//
+// The parameters become annotations of the LVMLogicalVolume, replacing the ones
+// of the previous class; a parameter the new class leaves out goes back to the
+// value the volume was created with, which its volume context holds. The
+// volume cleanup method and the contiguous flag also go into the
+// LVMLogicalVolume spec. The IO limits and the LV tags
+// are applied by the node plugin, which reads the annotations.
+func (d *Driver) ControllerModifyVolume(ctx context.Context, request *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
+	traceID := tracing.TraceID(ctx)
+	log := d.log.Named("ControllerModifyVolume").With("traceID", traceID, "volumeID", request.GetVolumeId())
+
+	log.Trace("start", "request", request.String())
+
+	volumeID := request.GetVolumeId()
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume id cannot be empty")
+	}
+
+	if !d.inFlight.InsertOperation(volumeID, "ControllerModifyVolume", traceID) {
+		return nil, d.inFlightAbortedErr(volumeID)
+	}
+	defer d.inFlight.Delete(volumeID)
+
+	mutableParams := request.GetMutableParameters()
+	if _, err := withMutableParameters(nil, mutableParams); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	llv, err := utils.GetLVMLogicalVolume(ctx, d.cl, volumeID, "")
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "LVMLogicalVolume %s not found", volumeID)
+		}
+		log.Error("unable to get the LVMLogicalVolume", logger.Err(err))
+		return nil, status.Errorf(codes.Internal, "error getting LVMLogicalVolume: %s", err.Error())
+	}
+
+	if err := validateMutableParameters(mutableParams, llv.Spec.Type); err != nil {
+		log.Error("invalid VolumeAttributesClass parameters", logger.Err(err))
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	modified := llv.DeepCopy()
+	if modified.Annotations == nil {
+		modified.Annotations = make(map[string]string, len(mutableParams))
+	}
+	for _, key := range mutableParameterKeys {
+		delete(modified.Annotations, key)
+	}
+	maps.Copy(modified.Annotations, mutableParams)
+
+	created, err := d.createdParameters(ctx, volumeID)
+	if err != nil {
+		log.Error("unable to get the PersistentVolume", logger.Err(err))
+		return nil, status.Errorf(codes.Internal, "error getting PersistentVolume: %s", err.Error())
+	}
+	params, _ := withMutableParameters(created, mutableParams)
+
+	modified.Spec.VolumeCleanup = nil
+	if method := params[LVMVolumeCleanupParamKey]; method != "" {
+		modified.Spec.VolumeCleanup = &method
+	}
+	if llv.Spec.Type == internal.LVMTypeThick {
+		contiguous := utils.IsContiguous(params, internal.LVMTypeThick)
+		if modified.Spec.Thick == nil {
+			modified.Spec.Thick = &v1alpha1.LVMLogicalVolumeThickSpec{}
+		}
+		modified.Spec.Thick.Contiguous = &contiguous
+	}
+
+	_, span := tracing.Start(ctx, "PatchLVMLogicalVolume", attribute.String("llv.name", llv.Name))
+	err = d.cl.Patch(ctx, modified, client.MergeFrom(llv))
+	tracing.End(span, err)
+	if err != nil {
+		log.Error("unable to patch the LVMLogicalVolume", logger.Err(err))
+		if kerrors.IsInvalid(err) {
+			return nil, status.Errorf(codes.InvalidArgument, "the LVMLogicalVolume rejects the change: %s", err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "error patching LVMLogicalVolume: %s", err.Error())
+	}
+
+	log.Info("volume modified successfully", "mutableParameters", fmt.Sprintf("%v", mutableParams))
+
 	return &csi.ControllerModifyVolumeResponse{}, nil
 }
@@ -14,23 +14,30 @@ import (
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/golang/protobuf/ptypes/timestamp"
-	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/encryption"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/events"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/tracing"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
 	"github.com/deckhouse/sds-node-configurator/api/v1alpha1"
 )
 
 func (d *Driver) CreateSnapshot(ctx context.Context, request *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
-	traceID := uuid.New().String()
+	traceID := tracing.TraceID(ctx)
 
 	log := d.log.Named("CreateSnapshot").With("traceID", traceID, "sourceVolumeID", request.SourceVolumeId)
 	log.Trace("start", "request", request.String())
 
+	if !d.inFlight.InsertOperation(request.Name, "CreateSnapshot", traceID) {
+		return nil, d.inFlightAbortedErr(request.Name)
+	}
+	defer d.inFlight.Delete(request.Name)
+
 	llv, err := utils.GetLVMLogicalVolume(ctx, d.cl, request.SourceVolumeId, "")
 	if err != nil {
 		log.Error("unable to get the source LVMLogicalVolume", logger.Err(err))
@@ -57,6 +64,9 @@ func (d *Driver) CreateSnapshot(ctx context.Context, request *csi.CreateSnapshot
 	}
 
 	if freeSpace.Value() < llv.Status.ActualSize.Value() {
+		d.events.Warning(ctx, events.Target{PVName: request.SourceVolumeId}, events.ReasonInsufficientSpace,
+			"Not enough space in thin pool %s of LVMVolumeGroup %s to snapshot the volume: %s free, at least %s needed",
+			llv.Spec.Thin.PoolName, lvg.Name, freeSpace.String(), llv.Status.ActualSize.String())
 		return nil, status.Errorf(
 			codes.FailedPrecondition,
 			"not enough space in pool %s (lvg %s): %s; need at least %s",
@@ -78,6 +88,14 @@ func (d *Driver) CreateSnapshot(ctx context.Context, request *csi.CreateSnapshot
 		actualNameOnTheNode = name
 	}
 
+	// The snapshot outlives its volume, and so must the passphrase the data of
+	// both is encrypted with. A volume without a Secret of its own has nothing
+	// to copy.
+	if _, err := encryption.CopySecret(ctx, d.cl, name, llv.Name); err != nil {
+		log.Error("unable to copy the passphrase of the volume", logger.Err(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
 	_, err = utils.CreateLVMLogicalVolumeSnapshot(
 		ctx,
 		d.cl,
@@ -138,14 +156,36 @@ func (d *Driver) DeleteSnapshot(ctx context.Context, request *csi.DeleteSnapshot
 		return nil, status.Error(codes.InvalidArgument, "SnapshotId ID cannot be empty")
 	}
 
-	traceID := uuid.New().String()
+	traceID := tracing.TraceID(ctx)
 	log := d.log.Named("DeleteSnapshot").With("traceID", traceID, "snapshotID", request.SnapshotId)
 	log.Trace("start", "request", request.String())
 
+	if !d.inFlight.InsertOperation(request.SnapshotId, "DeleteSnapshot", traceID) {
+		return nil, d.inFlightAbortedErr(request.SnapshotId)
+	}
+	defer d.inFlight.Delete(request.SnapshotId)
+
+	// The thin snapshot behind a read-only snapshot volume goes with the
+	// LVMLogicalVolumeSnapshot, so it is kept while such a volume exists.
+	pvName, err := d.snapshotUser(ctx, request.SnapshotId)
+	if err != nil {
+		log.Error("unable to list the PersistentVolumes", logger.Err(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if pvName != "" {
+		log.Info("the snapshot is used by a PersistentVolume", "pvName", pvName)
+		return nil, status.Errorf(codes.FailedPrecondition, "snapshot %q is used by PersistentVolume %q", request.SnapshotId, pvName)
+	}
+
 	if err := utils.DeleteLVMLogicalVolumeSnapshot(ctx, d.cl, log, request.SnapshotId); err != nil {
 		log.Error("unable to delete the LVMLogicalVolumeSnapshot", logger.Err(err))
 	}
 
+	if err := encryption.DeleteSecret(ctx, d.cl, request.SnapshotId); err != nil {
+		log.Error("unable to delete the Secret of the snapshot passphrase", logger.Err(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
 	log.Info("snapshot deleted successfully")
 
 	return &csi.DeleteSnapshotResponse{}, nil
@@ -17,12 +17,24 @@ limitations under the License.
 package driver
 
 import (
+	"context"
 	"testing"
 
+	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/qos"
 	"github.com/deckhouse/sds-node-configurator/api/v1alpha1"
 )
 
@@ -130,3 +142,128 @@ func TestResolveCapacityBytes(t *testing.T) {
 		assert.Equal(t, fallback.Value(), got)
 	})
 }
+
+func TestResolveLVName(t *testing.T) {
+	const volumeID = "pvc-3f1c9a2e-5b7d-4c1e-9f00-0123456789ab"
+
+	t.Run("names_the_lv_after_the_volume_without_a_template", func(t *testing.T) {
+		got, err := resolveLVName(map[string]string{}, volumeID)
+		assert.NoError(t, err)
+		assert.Equal(t, volumeID, got)
+	})
+
+	t.Run("renders_the_template_of_the_class", func(t *testing.T) {
+		got, err := resolveLVName(map[string]string{
+			internal.LVNameTemplateKey: "{{.Namespace}}-{{.PVCName}}-{{.Short}}",
+			internal.PVCNamespaceKey:   "db",
+			internal.PVCNameKey:        "data-postgres-0",
+		}, volumeID)
+		assert.NoError(t, err)
+		assert.Equal(t, "db-data-postgres-0-3f1c9a2e", got)
+	})
+
+	t.Run("rejects_a_name_lvm_would_not_accept", func(t *testing.T) {
+		// Without --extra-create-metadata the claim is unknown and the name
+		// would start with a dash.
+		_, err := resolveLVName(map[string]string{
+			internal.LVNameTemplateKey: "{{.Namespace}}-{{.PVCName}}-{{.Short}}",
+		}, volumeID)
+		assert.Error(t, err)
+	})
+}
+
+func TestWithMutableParameters(t *testing.T) {
+	class := map[string]string{
+		qos.ReadIOPSKey:  "1000",
+		qos.WriteIOPSKey: "500",
+	}
+
+	t.Run("lets_the_volume_attributes_class_override_the_class", func(t *testing.T) {
+		got, err := withMutableParameters(class, map[string]string{qos.WriteIOPSKey: "0", internal.LVTagsKey: "tier:gold"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{qos.ReadIOPSKey: "1000", qos.WriteIOPSKey: "0", internal.LVTagsKey: "tier:gold"}, got)
+		assert.Equal(t, "500", class[qos.WriteIOPSKey], "the class parameters are left alone")
+	})
+
+	t.Run("rejects_unknown_volume_attributes_class_parameters", func(t *testing.T) {
+		_, err := withMutableParameters(class, map[string]string{internal.LvmTypeKey: internal.LVMTypeThin})
+		assert.Error(t, err)
+	})
+}
+
+func TestValidateMutableParameters(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		params  map[string]string
+		lvmType string
+		wantErr bool
+	}{
+		{name: "io_limits", params: map[string]string{qos.ReadBPSKey: "100Mi"}, lvmType: internal.LVMTypeThin},
+		{name: "invalid_io_limit", params: map[string]string{qos.ReadBPSKey: "fast"}, lvmType: internal.LVMTypeThin, wantErr: true},
+		{name: "contiguous_thick", params: map[string]string{internal.LVMVThickContiguousParamKey: "true"}, lvmType: internal.LVMTypeThick},
+		{name: "contiguous_thin", params: map[string]string{internal.LVMVThickContiguousParamKey: "true"}, lvmType: internal.LVMTypeThin, wantErr: true},
+		{name: "contiguous_not_a_bool", params: map[string]string{internal.LVMVThickContiguousParamKey: "yes"}, lvmType: internal.LVMTypeThick, wantErr: true},
+		{name: "cleanup_turned_off", params: map[string]string{LVMVolumeCleanupParamKey: ""}, lvmType: internal.LVMTypeThick},
+		{name: "unknown_cleanup", params: map[string]string{LVMVolumeCleanupParamKey: "Shred"}, lvmType: internal.LVMTypeThick, wantErr: true},
+		{name: "tags", params: map[string]string{internal.LVTagsKey: "backup=daily,tier:gold"}, lvmType: internal.LVMTypeThin},
+		{name: "reserved_tag", params: map[string]string{internal.LVTagsKey: "storage.deckhouse.io/enabled=true"}, lvmType: internal.LVMTypeThin, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateMutableParameters(tc.params, tc.lvmType)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// A parameter the new VolumeAttributesClass leaves out goes back to the value
+// of the volume context, also in the LVMLogicalVolume spec.
+func TestControllerModifyVolumeRestoresCreatedParameters(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		created map[string]string
+		want    *string
+	}{
+		{name: "created_without_cleanup", created: map[string]string{}},
+		{name: "created_with_cleanup", created: map[string]string{LVMVolumeCleanupParamKey: "RandomFillSinglePass"}, want: ptr.To("RandomFillSinglePass")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			llv := &v1alpha1.LVMLogicalVolume{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pvc-1",
+					Annotations: map[string]string{LVMVolumeCleanupParamKey: "Discard"},
+				},
+				Spec: v1alpha1.LVMLogicalVolumeSpec{Type: internal.LVMTypeThin, VolumeCleanup: ptr.To("Discard")},
+			}
+			pv := &corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+				Spec: corev1.PersistentVolumeSpec{
+					PersistentVolumeSource: corev1.PersistentVolumeSource{
+						CSI: &corev1.CSIPersistentVolumeSource{Driver: DefaultDriverName, VolumeHandle: "pvc-1", VolumeAttributes: tc.created},
+					},
+				},
+			}
+
+			scheme := runtime.NewScheme()
+			require.NoError(t, clientgoscheme.AddToScheme(scheme))
+			require.NoError(t, v1alpha1.AddToScheme(scheme))
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(llv, pv).Build()
+			d := &Driver{cl: cl, log: logger.NewNop(), inFlight: internal.NewInFlight()}
+
+			_, err := d.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+				VolumeId:          "pvc-1",
+				MutableParameters: map[string]string{qos.Keys[0]: "100"},
+			})
+			require.NoError(t, err)
+
+			modified := &v1alpha1.LVMLogicalVolume{}
+			require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Name: "pvc-1"}, modified))
+			assert.Equal(t, tc.want, modified.Spec.VolumeCleanup)
+			assert.NotContains(t, modified.Annotations, LVMVolumeCleanupParamKey)
+			assert.Equal(t, "100", modified.Annotations[qos.Keys[0]])
+		})
+	}
+}
@@ -27,17 +27,21 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/events"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/monitoring"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/tracing"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
 )
 
@@ -49,6 +53,52 @@ const (
 	// http handler on.
 	DefaultAddress           = "127.0.0.1:12302"
 	defaultWaitActionTimeout = 5 * time.Minute
+
+	// RoleController and RoleNode are the roles the plugin is started in. Both
+	// run the same binary; the role decides which node-local checks apply. An
+	// empty role runs everything, as the plugin did before roles existed.
+	RoleController = "controller"
+	RoleNode       = "node"
+
+	// inFlightMetricsInterval is how often the age of the oldest in-flight
+	// operation is published. Operations stuck badly enough to matter are stuck
+	// for minutes, so a coarse interval loses nothing.
+	inFlightMetricsInterval = 10 * time.Second
+
+	// hostCgroupRoot is where the node plugin has the cgroup v2 hierarchy of the
+	// host mounted, see templates/csi/controller.yaml.
+	hostCgroupRoot = "/host/sys/fs/cgroup"
+	// hostProcRoot is where the node plugin has the /proc of the host
+	// mounted, to find the processes holding a busy mount.
+	hostProcRoot = "/host/proc"
+	// kubeletDir is where the node plugin has the root directory of the
+	// kubelet mounted, at the path it has on the host. The CSI socket is in
+	// another directory of the host.
+	kubeletDir = "/var/lib/kubelet"
+	// publishedVolumesFile is kept next to the CSI socket, in the plugin
+	// directory on the host, and outlives the plugin container.
+	publishedVolumesFile = "published-volumes.json"
+	// volumeAttributesSyncInterval is how often the IO limits of the published
+	// volumes and the LV tags of all the volumes of the node are applied again.
+	// It catches a pod cgroup the kubelet has recreated and a volume modified
+	// with ControllerModifyVolume.
+	volumeAttributesSyncInterval = time.Minute
+	// stagedVolumesFile is kept next to publishedVolumesFile.
+	stagedVolumesFile = "staged-volumes.json"
+	// resizeDir is where in the plugin directory the filesystems of the
+	// volumes that are not mounted are mounted to be resized.
+	resizeDir = "resize"
+	// volumeMetricsInterval is how often the usage metrics of the staged
+	// volumes are collected, as often as the kubelet collects its volume stats
+	// by default.
+	volumeMetricsInterval = time.Minute
+	// trimCheckInterval is how often the staged volumes are checked for a
+	// periodic discard that is due. The intervals of the classes are whole
+	// minutes.
+	trimCheckInterval = time.Minute
+	// staleMountsInterval is how often the mounts of the driver are checked
+	// for the ones left behind.
+	staleMountsInterval = 10 * time.Minute
 )
 
 var (
@@ -62,18 +112,49 @@ type Driver struct {
 	csiAddress        string
 	address           string
 	hostID            string
+	role              string
 	waitActionTimeout time.Duration
+	drainTimeout      time.Duration
 
 	srv     *grpc.Server
 	httpSrv http.Server
 	log     logger.Logger
 	metrics monitoring.Recorder
+	events  events.Recorder
 
 	readyMu      sync.Mutex // protects ready
 	ready        bool
 	cl           client.Client
 	storeManager utils.NodeStoreManager
 	inFlight     *internal.InFlight
+	published    *internal.PublishedVolumes
+	staged       *internal.StagedVolumes
+	cgroupRoot   string
+	// pluginDir is the directory of the CSI socket on the host.
+	pluginDir string
+	// kubeletDir is the root directory of the kubelet.
+	kubeletDir string
+	// lazyUnmountAfter is how many times in a row the unmount of a busy
+	// target fails before it is unmounted lazily; 0 never does.
+	lazyUnmountAfter int
+	unmountFailures  unmountFailures
+	// xfsSupport is what the node supports of utils.XFSFeatures, probed once
+	// the node plugin starts.
+	xfsSupport map[string]utils.XFSSupport
+	// autoscalingReport is the last usage reported to the controller in the
+	// internal.AutoscalingAnnotation of the node, once autoscalingReported.
+	autoscalingReport   map[string]autoscalingUsage
+	autoscalingReported bool
+
+	// probeHealth backs Identity.Probe, readinessHealth and livenessHealth back
+	// /readyz and /healthz. Probe only checks the plugin itself: the
+	// livenessprobe sidecar restarts the container on a failed Probe, which
+	// would not bring back the API server. Dependencies such as the API
+	// server are left to /readyz, and the CSI socket check to both HTTP
+	// endpoints, as the Probe call has just come in over that socket.
+	probeHealth     *HealthChecker
+	readinessHealth *HealthChecker
+	livenessHealth  *HealthChecker
 
 	csi.UnimplementedControllerServer
 	csi.UnimplementedIdentityServer
@@ -83,48 +164,66 @@ type Driver struct {
 // NewDriver returns a CSI plugin that contains the necessary gRPC
 // interfaces to interact with Kubernetes over unix domain sockets for
 // managing  disks
-func NewDriver(csiAddress, driverName, address string, nodeName *string, log logger.Logger, metrics monitoring.Recorder, cl client.Client) (*Driver, error) {
+//
+// drainTimeout bounds how long Run waits for in-flight operations once its
+// context is cancelled. lazyUnmountAfter, when not 0, is how many times in a
+// row a busy mount fails to unmount before it is unmounted lazily.
+func NewDriver(csiAddress, driverName, address string, nodeName *string, role string, log logger.Logger, metrics monitoring.Recorder, eventRecorder events.Recorder, cl client.Client, drainTimeout time.Duration, lazyUnmountAfter int) (*Driver, error) {
 	if driverName == "" {
 		driverName = DefaultDriverName
 	}
 
 	st := utils.NewStore(log)
 
+	var probeChecks []HealthCheck
+	if role != RoleController {
+		probeChecks = append(probeChecks, NewBinariesCheck(nodeBinaries...), NewDevCheck("/dev"))
+	}
+	readinessChecks := append([]HealthCheck{NewKubeAPICheck(cl)}, probeChecks...)
+	socketCheck := NewCSISocketCheck(csiAddress)
+
+	socketPath, err := socketPath(csiAddress)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Driver{
 		name:              driverName,
 		hostID:            *nodeName,
+		role:              role,
 		csiAddress:        csiAddress,
 		address:           address,
 		log:               log,
 		metrics:           metrics,
+		events:            eventRecorder,
 		waitActionTimeout: defaultWaitActionTimeout,
+		drainTimeout:      drainTimeout,
 		cl:                cl,
 		storeManager:      st,
 		inFlight:          internal.NewInFlight(),
+		published:         internal.NewPublishedVolumes(filepath.Join(filepath.Dir(socketPath), publishedVolumesFile)),
+		staged:            internal.NewStagedVolumes(filepath.Join(filepath.Dir(socketPath), stagedVolumesFile)),
+		pluginDir:         filepath.Dir(socketPath),
+		kubeletDir:        kubeletDir,
+		lazyUnmountAfter:  lazyUnmountAfter,
+		cgroupRoot:        hostCgroupRoot,
+		probeHealth:       NewHealthChecker(probeChecks...),
+		readinessHealth:   NewHealthChecker(append(readinessChecks, socketCheck)...),
+		livenessHealth:    NewHealthChecker(socketCheck),
 	}, nil
 }
 
 func (d *Driver) Run(ctx context.Context) error {
 	log := d.log.Named("Run")
 
-	u, err := url.Parse(d.csiAddress)
+	grpcAddr, err := socketPath(d.csiAddress)
 	if err != nil {
-		return fmt.Errorf("unable to parse address: %q", err)
-	}
-
-	grpcAddr := path.Join(u.Host, filepath.FromSlash(u.Path))
-	if u.Host == "" {
-		grpcAddr = filepath.FromSlash(u.Path)
+		return err
 	}
 
-	// These three values used to be dumped to stdout with fmt.Print, bypassing the
+	// This value used to be dumped to stdout with fmt.Print, bypassing the
 	// logger and the configured level entirely.
-	log.Trace("resolved the listen addresses", "csiAddress", d.csiAddress, "parsedURL", u.String(), "grpcAddr", grpcAddr)
-
-	// CSI plugins talk only over UNIX sockets currently
-	if u.Scheme != "unix" {
-		return fmt.Errorf("currently only unix domain sockets are supported, have: %s", u.Scheme)
-	}
+	log.Trace("resolved the listen addresses", "csiAddress", d.csiAddress, "grpcAddr", grpcAddr)
 	// remove the socket if it's already there. This can happen if we
 	// deploy a new version and the socket was created from the old running
 	// plugin.
@@ -133,17 +232,28 @@ func (d *Driver) Run(ctx context.Context) error {
 		return fmt.Errorf("failed to remove unix domain socket file %s, error: %s", grpcAddr, err)
 	}
 
-	grpcListener, err := net.Listen(u.Scheme, grpcAddr)
+	// The probe is over before the first NodeStageVolume can come in.
+	if d.role != RoleController {
+		d.probeNodeCapabilities(ctx, filepath.Dir(grpcAddr))
+	}
+
+	grpcListener, err := net.Listen("unix", grpcAddr)
 	if err != nil {
 		return fmt.Errorf("failed to listen: %v", err)
 	}
 
 	// log response errors and record the duration and status of every call.
 	// Doing it in the interceptor covers each CSI method exactly once, including
-	// the ones inherited from the Unimplemented*Server embeds.
+	// the ones inherited from the Unimplemented*Server embeds. The same goes for
+	// the server span every RPC runs under.
 	errHandler := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		start := time.Now()
+		ctx, span := tracing.Start(ctx, strings.TrimPrefix(info.FullMethod, "/"), attribute.String("rpc.method", info.FullMethod))
+		if r, ok := req.(interface{ GetVolumeId() string }); ok && r.GetVolumeId() != "" {
+			span.SetAttributes(attribute.String("csi.volume_id", r.GetVolumeId()))
+		}
 		resp, err := handler(ctx, req)
+		tracing.End(span, err)
 		d.metrics.ObserveOperation(info.FullMethod, start, err)
 		if err != nil {
 			log.Error("the CSI method failed", logger.Err(err), slog.String("method", info.FullMethod))
@@ -179,16 +289,49 @@ func (d *Driver) Run(ctx context.Context) error {
 		return d.httpSrv.Shutdown(context.Background())
 	})
 	eg.Go(func() error {
-		go func() {
-			<-ctx.Done()
-			log.Info("server stopped")
-			d.readyMu.Lock()
-			d.ready = false
-			d.readyMu.Unlock()
-			d.srv.GracefulStop()
-		}()
 		return d.srv.Serve(grpcListener)
 	})
+	eg.Go(func() error {
+		d.publishInFlightMetrics(ctx)
+		return nil
+	})
+	if d.role != RoleController {
+		if err := d.published.Load(); err != nil {
+			log.Error("unable to load the published volumes", logger.Err(err))
+		}
+		eg.Go(func() error {
+			d.syncPublishedVolumes(ctx)
+			return nil
+		})
+		if err := d.staged.Load(); err != nil {
+			log.Error("unable to load the staged volumes", logger.Err(err))
+		}
+		eg.Go(func() error {
+			d.collectVolumeMetrics(ctx)
+			return nil
+		})
+		eg.Go(func() error {
+			d.trimVolumes(ctx)
+			return nil
+		})
+		eg.Go(func() error {
+			d.cleanStaleMounts(ctx)
+			return nil
+		})
+	}
+	if d.role != RoleNode {
+		eg.Go(func() error {
+			d.autoscaleVolumes(ctx)
+			return nil
+		})
+	}
+	// Serve returns as soon as GracefulStop closes the listener, long before the
+	// pending RPCs are done, so the drain runs in a goroutine of its own and
+	// Run returns only once it is over.
+	eg.Go(func() error {
+		<-ctx.Done()
+		return d.drain(log)
+	})
 	eg.Go(func() error {
 		err := d.httpSrv.Serve(httpListener)
 		if errors.Is(err, http.ErrServerClosed) {
@@ -199,3 +342,125 @@ func (d *Driver) Run(ctx context.Context) error {
 
 	return eg.Wait()
 }
+
+// ReadinessHandler serves the readiness checks for the /readyz endpoint.
+func (d *Driver) ReadinessHandler() http.Handler {
+	return d.readinessHealth
+}
+
+// LivenessHandler serves the liveness checks for the /healthz endpoint. Only the
+// CSI socket is checked there: a restart does not bring back an unreachable API
+// server or a missing mkfs, it only adds a crash loop on top.
+func (d *Driver) LivenessHandler() http.Handler {
+	return d.livenessHealth
+}
+
+// InFlightHandler serves the table of in-flight volume operations as JSON, for
+// the /debug/inflight endpoint.
+func (d *Driver) InFlightHandler() http.Handler {
+	return d.inFlight
+}
+
+// publishInFlightMetrics keeps the oldest in-flight operation age gauge up to
+// date until ctx is cancelled.
+func (d *Driver) publishInFlightMetrics(ctx context.Context) {
+	ticker := time.NewTicker(inFlightMetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		d.metrics.SetInFlightOldestAge(d.inFlight.OldestAge())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncPublishedVolumes applies the IO limits of the published volumes and the
+// LV tags of the staged ones every volumeAttributesSyncInterval until ctx is
+// cancelled.
+func (d *Driver) syncPublishedVolumes(ctx context.Context) {
+	log := d.log.Named("syncPublishedVolumes")
+
+	ticker := time.NewTicker(volumeAttributesSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		for target, volume := range d.published.List() {
+			if err := d.syncVolumeAttributes(ctx, log.With("volumeID", volume.VolumeID), target, volume); err != nil {
+				log.Warn("unable to apply the attributes of a published volume", logger.Err(err), slog.String("volumeID", volume.VolumeID), slog.String("targetPath", target))
+			}
+		}
+		d.syncLVTags(ctx, log)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// socketPath returns the path of the unix socket the CSI address names. CSI
+// plugins talk only over unix sockets currently.
+func socketPath(csiAddress string) (string, error) {
+	u, err := url.Parse(csiAddress)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse address: %q", err)
+	}
+
+	if u.Scheme != "unix" {
+		return "", fmt.Errorf("currently only unix domain sockets are supported, have: %s", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return filepath.FromSlash(u.Path), nil
+	}
+	return path.Join(u.Host, filepath.FromSlash(u.Path)), nil
+}
+
+// drain stops the gRPC server gracefully: new RPCs are refused while the ones
+// already being served — a CreateVolume waiting for its LVMLogicalVolume, an
+// mkfs in NodeStageVolume — are given up to drainTimeout to finish.
+//
+// Cutting those off half-way is what leaves a half-created LVMLogicalVolume or a
+// half-formatted device behind, so the server is only stopped forcibly once
+// the deadline has passed. Whatever is still tracked in inFlight at that point,
+// controller and node operations alike, is logged, counted in the abandoned
+// operations metric and returned in the error.
+func (d *Driver) drain(log logger.Logger) error {
+	log.Info("shutting down, draining in-flight operations", "timeout", d.drainTimeout.String(), "inFlight", d.inFlight.Keys())
+
+	d.readyMu.Lock()
+	d.ready = false
+	d.readyMu.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		d.srv.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(d.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+		log.Info("server stopped, all in-flight operations finished")
+		return nil
+	case <-timer.C:
+	}
+
+	abandoned := d.inFlight.Keys()
+	for _, op := range d.inFlight.Operations() {
+		d.metrics.AddAbandonedOperation(op.RPC)
+	}
+	log.Warn("the drain timeout is exceeded, abandoning in-flight operations", "timeout", d.drainTimeout.String(), "abandoned", abandoned)
+	d.srv.Stop()
+	<-stopped
+	log.Info("server stopped")
+
+	return fmt.Errorf("abandoned %d in-flight operations after %s: %v", len(abandoned), d.drainTimeout, abandoned)
+}
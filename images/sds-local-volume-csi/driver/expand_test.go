@@ -0,0 +1,143 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	mountutils "k8s.io/mount-utils"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+	"github.com/deckhouse/sds-node-configurator/api/v1alpha1"
+)
+
+// expandStore has an xfs filesystem mounted at the paths in mounted, and
+// records what it resizes.
+type expandStore struct {
+	utils.NodeStoreManager
+	mounted   []string
+	resized   string
+	offline   string
+	mountOpts []string
+}
+
+func (s *expandStore) PathExists(string) (bool, error) {
+	return false, nil
+}
+
+func (s *expandStore) FindMount(path string) (*mountutils.MountPoint, error) {
+	for _, mounted := range s.mounted {
+		if mounted == path {
+			return &mountutils.MountPoint{Path: path}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *expandStore) ResizeFS(target string) error {
+	s.resized = target
+	return nil
+}
+
+func (s *expandStore) DiskFormat(string) (string, error) {
+	return internal.FSTypeXfs, nil
+}
+
+func (s *expandStore) ResizeFSOffline(devicePath, _, _ string, mountOpts []string) (int64, error) {
+	s.offline = devicePath
+	s.mountOpts = mountOpts
+	return 1 << 30, nil
+}
+
+// A volume that is only staged grows through its staging path; one mounted
+// nowhere and not staged through its LV on the node.
+func TestNodeExpandVolumeNotPublished(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	lvg := &v1alpha1.LVMVolumeGroup{ObjectMeta: metav1.ObjectMeta{Name: "lvg-1"}}
+	lvg.Spec.Local.NodeName = "node-1"
+	lvg.Spec.ActualVGNameOnTheNode = "vg-1"
+	llv := &v1alpha1.LVMLogicalVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+		Spec:       v1alpha1.LVMLogicalVolumeSpec{ActualLVNameOnTheNode: "pvc-1", LVMVolumeGroupName: "lvg-1"},
+	}
+	stagingPath := t.TempDir()
+	volumeCapability := &csi.VolumeCapability{AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}}
+
+	for _, tc := range []struct {
+		name    string
+		mounted []string
+		resized string
+		offline string
+	}{
+		{name: "staged", mounted: []string{stagingPath}, resized: stagingPath},
+		{name: "not_staged", offline: "/dev/vg-1/pvc-1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &expandStore{mounted: tc.mounted}
+			d := &Driver{
+				hostID:       "node-1",
+				log:          logger.NewNop(),
+				cl:           fake.NewClientBuilder().WithScheme(scheme).WithObjects(lvg, llv).Build(),
+				storeManager: store,
+				inFlight:     internal.NewInFlight(),
+				staged:       internal.NewStagedVolumes(filepath.Join(t.TempDir(), stagedVolumesFile)),
+			}
+
+			_, err := d.NodeExpandVolume(context.Background(), &csi.NodeExpandVolumeRequest{
+				VolumeId:          "pvc-1",
+				VolumePath:        "/var/lib/kubelet/pods/uid-1/volumes/kubernetes.io~csi/pvc-1/mount",
+				StagingTargetPath: stagingPath,
+				VolumeCapability:  volumeCapability,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.resized, store.resized)
+			assert.Equal(t, tc.offline, store.offline)
+			if tc.offline != "" {
+				assert.Equal(t, []string{"nouuid"}, store.mountOpts)
+			}
+		})
+	}
+}
+
+// The growth of the LV of a volume the node has no record of is not taken for
+// granted.
+func TestCheckLVGrownUnknownVolume(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	d := &Driver{
+		hostID:    "node-1",
+		cl:        fake.NewClientBuilder().WithScheme(scheme).Build(),
+		staged:    internal.NewStagedVolumes(filepath.Join(t.TempDir(), stagedVolumesFile)),
+		published: internal.NewPublishedVolumes(filepath.Join(t.TempDir(), publishedVolumesFile)),
+	}
+
+	err := d.checkLVGrown(context.Background(), logger.NewNop(), "pvc-1", "/var/lib/kubelet/pods/uid-1/volumes/kubernetes.io~csi/pvc-1/mount", 1<<30)
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/events"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/tracing"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+)
+
+// fsckFailed is the result label of a check that could not be run.
+const fsckFailed = "failed"
+
+// checkFilesystem checks the filesystem on devPath before NodeStageVolume
+// mounts it at target, according to the fsck policy of the class, and tells
+// whether the policy applied: the mount then skips the fsck -a of mount-utils.
+//
+// A volume whose check found corruption or failed is not mounted, unless its
+// PVC has the fsck override annotation.
+func (d *Driver) checkFilesystem(ctx context.Context, log logger.Logger, params map[string]string, devPath, target, fsType string) (bool, error) {
+	policy := params[internal.FsckPolicyKey]
+	if policy == "" {
+		return false, nil
+	}
+
+	// A staged volume is left as it is by NodeStageVolumeFS.
+	mount, err := d.storeManager.FindMount(target)
+	if err != nil {
+		return false, status.Errorf(codes.Internal, "[NodeStageVolume] Unable to check whether %q is mounted: %v", target, err)
+	}
+	if mount != nil {
+		return false, nil
+	}
+
+	// A blank device is formatted, and one with another filesystem fails to
+	// mount.
+	format, err := d.storeManager.DiskFormat(devPath)
+	if err != nil {
+		return false, status.Errorf(codes.Internal, "[NodeStageVolume] Unable to get the filesystem of device %q: %v", devPath, err)
+	}
+	if format != fsType {
+		return false, nil
+	}
+
+	if policy == internal.FsckPolicyNone {
+		return true, nil
+	}
+
+	log = log.With("devicePath", devPath, "fsType", fsType, "fsckPolicy", policy)
+	pvc := events.TargetFromParameters(params)
+
+	_, span := tracing.Start(ctx, "CheckFS", attribute.String("device", devPath), attribute.String("fs.type", fsType))
+	result, output, err := d.storeManager.CheckFS(devPath, fsType, policy)
+	tracing.End(span, err)
+	if err != nil {
+		d.metrics.AddFsck(fsType, fsckFailed)
+		log.Error("unable to check the filesystem", logger.Err(err))
+		if d.fsckOverridden(ctx, log, pvc) {
+			d.events.Warning(ctx, pvc, events.ReasonFilesystemCheckFailed, "Unable to check the %s filesystem of the volume on node %s, mounting it as the PVC has the %s annotation: %v", fsType, d.hostID, internal.FsckOverrideAnnotation, err)
+			return true, nil
+		}
+		d.events.Warning(ctx, pvc, events.ReasonFilesystemCheckFailed, "Unable to check the %s filesystem of the volume on node %s: %v", fsType, d.hostID, err)
+		return false, status.Errorf(codes.Internal, "[NodeStageVolume] Unable to check the %s filesystem of device %q: %v", fsType, devPath, err)
+	}
+	d.metrics.AddFsck(fsType, string(result))
+
+	switch result {
+	case utils.FsckClean:
+		log.Info("the filesystem is clean")
+		d.events.Normal(ctx, pvc, events.ReasonFilesystemChecked, "The %s filesystem of the volume was checked on node %s and is clean", fsType, d.hostID)
+	case utils.FsckRepaired:
+		log.Warn("the check repaired the filesystem", slog.String("output", output))
+		d.events.Normal(ctx, pvc, events.ReasonFilesystemRepaired, "The check of the %s filesystem of the volume on node %s repaired it", fsType, d.hostID)
+	case utils.FsckCorrupted:
+		log.Error("the check found corruption of the filesystem", slog.String("output", output))
+		if d.fsckOverridden(ctx, log, pvc) {
+			d.events.Warning(ctx, pvc, events.ReasonFilesystemCorrupted, "The check of the %s filesystem of the volume on node %s found corruption, mounting it as the PVC has the %s annotation", fsType, d.hostID, internal.FsckOverrideAnnotation)
+			return true, nil
+		}
+		d.events.Warning(ctx, pvc, events.ReasonFilesystemCorrupted, "The check of the %s filesystem of the volume on node %s found corruption it did not repair. Repair it, or set the %s annotation of the PVC to \"true\" to mount it anyway", fsType, d.hostID, internal.FsckOverrideAnnotation)
+		return false, status.Errorf(codes.FailedPrecondition, "[NodeStageVolume] The %s filesystem of device %q is corrupted", fsType, devPath)
+	case utils.FsckSkipped:
+		log.Debug("not checking the filesystem", slog.String("reason", output))
+	}
+
+	return true, nil
+}
+
+// fsckOverridden tells whether the PVC of the volume asks for it to be mounted
+// whatever its filesystem check found.
+func (d *Driver) fsckOverridden(ctx context.Context, log logger.Logger, target events.Target) bool {
+	if target.PVCName == "" || d.cl == nil {
+		return false
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := d.cl.Get(ctx, client.ObjectKey{Namespace: target.PVCNamespace, Name: target.PVCName}, pvc); err != nil {
+		log.Warn("unable to get the PersistentVolumeClaim to check the fsck override", logger.Err(err))
+		return false
+	}
+
+	return pvc.Annotations[internal.FsckOverrideAnnotation] == "true"
+}
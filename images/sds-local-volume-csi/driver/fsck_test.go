@@ -0,0 +1,124 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	mountutils "k8s.io/mount-utils"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/events"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+)
+
+// fsckStore holds an unmounted ext4 filesystem that checks with result.
+type fsckStore struct {
+	utils.NodeStoreManager
+	format  string
+	result  utils.FsckResult
+	checked bool
+}
+
+func (s *fsckStore) FindMount(string) (*mountutils.MountPoint, error) {
+	return nil, nil
+}
+
+func (s *fsckStore) DiskFormat(string) (string, error) {
+	return s.format, nil
+}
+
+func (s *fsckStore) CheckFS(string, string, string) (utils.FsckResult, string, error) {
+	s.checked = true
+	return s.result, "", nil
+}
+
+func TestCheckFilesystem(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		policy   string
+		format   string
+		result   utils.FsckResult
+		override bool
+		want     bool
+		checked  bool
+		code     codes.Code
+		event    string
+	}{
+		{name: "no_policy", format: "ext4", result: utils.FsckClean},
+		{name: "none", policy: "None", format: "ext4", result: utils.FsckCorrupted, want: true},
+		{name: "blank_device", policy: "Preen", result: utils.FsckClean},
+		{name: "clean", policy: "Preen", format: "ext4", result: utils.FsckClean, want: true, checked: true, event: "Normal FilesystemChecked"},
+		{name: "repaired", policy: "Preen", format: "ext4", result: utils.FsckRepaired, want: true, checked: true, event: "Normal FilesystemRepaired"},
+		{name: "clean_unmount", policy: "ForceOnDirty", format: "ext4", result: utils.FsckSkipped, want: true, checked: true},
+		{name: "corrupted", policy: "Preen", format: "ext4", result: utils.FsckCorrupted, checked: true, code: codes.FailedPrecondition, event: "Warning FilesystemCorrupted"},
+		{name: "corrupted_override", policy: "Preen", format: "ext4", result: utils.FsckCorrupted, override: true, want: true, checked: true, event: "Warning FilesystemCorrupted"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+			pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "db"}}
+			if tc.override {
+				pvc.Annotations = map[string]string{internal.FsckOverrideAnnotation: "true"}
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pvc).Build()
+			recorder := record.NewFakeRecorder(10)
+			store := &fsckStore{format: tc.format, result: tc.result}
+			d := &Driver{
+				hostID:       "node-1",
+				cl:           cl,
+				storeManager: store,
+				events:       events.NewRecorderFor(cl, recorder, logger.NewNop()),
+			}
+
+			params := map[string]string{
+				internal.FsckPolicyKey:   tc.policy,
+				internal.PVCNameKey:      "data",
+				internal.PVCNamespaceKey: "db",
+			}
+			got, err := d.checkFilesystem(context.Background(), logger.NewNop(), params, "/dev/vg/pvc-1", "/staging/pvc-1", "ext4")
+			if tc.code != codes.OK {
+				assert.Equal(t, tc.code, status.Code(err))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.checked, store.checked)
+
+			select {
+			case e := <-recorder.Events:
+				assert.Contains(t, e, tc.event)
+				assert.NotEmpty(t, tc.event, "unexpected event %q", e)
+			default:
+				assert.Empty(t, tc.event, "no event")
+			}
+		})
+	}
+}
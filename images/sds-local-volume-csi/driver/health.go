@@ -16,13 +16,71 @@ limitations under the License.
 
 package driver
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	snc "github.com/deckhouse/sds-node-configurator/api/v1alpha1"
+)
+
+// healthCheckTimeout bounds a single check. A check that hangs is reported as
+// failed rather than holding up the probe past the kubelet's own timeout.
+const healthCheckTimeout = 5 * time.Second
+
+// nodeBinaries are the filesystem tools the node plugin shells out to through
+// mount-utils and for btrfs, lvs, which resolves the devices of the volumes,
+// nsenter, which runs the LVM of the host to change an LV, blockdev, which
+// makes the snapshots mounted read-only, cryptsetup for the encrypted volumes
+// and fstrim for the periodic discard. A node image missing one of them stages
+// or expands volumes only until the first volume that needs it shows up.
+var nodeBinaries = []string{
+	"mkfs.ext4", "mkfs.ext3", "resize2fs", "e2fsck", "dumpe2fs",
+	"mkfs.xfs", "xfs_growfs", "xfs_logprint", "xfs_repair",
+	"mkfs.btrfs", "btrfs", "btrfstune",
+	"lvs", "nsenter", "blockdev", "cryptsetup", "fstrim",
+}
 
 // HealthCheck is the interface that must be implemented to be compatible with
 // `HealthChecker`.
 type HealthCheck interface {
 	Name() string
-	Check(ctx context.Context)
+	Check(ctx context.Context) error
+}
+
+// HealthCheckResult is the outcome of a single check, as reported in the JSON
+// body of the probe endpoints.
+type HealthCheckResult struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// HealthReport is the outcome of every check of a HealthChecker.
+type HealthReport struct {
+	OK     bool                `json:"ok"`
+	Checks []HealthCheckResult `json:"checks"`
+}
+
+// Failed returns the results of the checks that did not pass.
+func (r HealthReport) Failed() []HealthCheckResult {
+	var failed []HealthCheckResult
+	for _, c := range r.Checks {
+		if !c.OK {
+			failed = append(failed, c)
+		}
+	}
+	return failed
 }
 
 // HealthChecker helps with writing multi component health checkers.
@@ -36,3 +94,146 @@ func NewHealthChecker(checks ...HealthCheck) *HealthChecker {
 		checks: checks,
 	}
 }
+
+// Check runs every check, each under its own timeout, and reports all of them:
+// a failing check does not hide the state of the ones after it.
+func (hc *HealthChecker) Check(ctx context.Context) HealthReport {
+	report := HealthReport{
+		OK:     true,
+		Checks: make([]HealthCheckResult, 0, len(hc.checks)),
+	}
+
+	for _, check := range hc.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		err := check.Check(checkCtx)
+		cancel()
+
+		result := HealthCheckResult{Name: check.Name(), OK: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+			report.OK = false
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	return report
+}
+
+// ServeHTTP answers 200 when every check passes and 503 otherwise, with the
+// per-check report as the JSON body either way.
+func (hc *HealthChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := hc.Check(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.OK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// kubeAPICheck verifies that the Kubernetes API server is reachable and that
+// the driver may still read the resources it provisions from.
+type kubeAPICheck struct {
+	cl client.Client
+}
+
+// NewKubeAPICheck returns a check listing a single LVMVolumeGroup.
+func NewKubeAPICheck(cl client.Client) HealthCheck {
+	return kubeAPICheck{cl: cl}
+}
+
+func (c kubeAPICheck) Name() string {
+	return "kube-api"
+}
+
+func (c kubeAPICheck) Check(ctx context.Context) error {
+	if err := c.cl.List(ctx, &snc.LVMVolumeGroupList{}, client.Limit(1)); err != nil {
+		return fmt.Errorf("unable to list LVMVolumeGroups: %w", err)
+	}
+	return nil
+}
+
+// csiSocketCheck verifies that the CSI gRPC server answers on its socket, the
+// way the sidecars and the kubelet reach it.
+type csiSocketCheck struct {
+	csiAddress string
+}
+
+// NewCSISocketCheck returns a check calling GetPluginInfo over csiAddress.
+// GetPluginInfo is used rather than Probe because Probe runs the readiness
+// checks itself.
+func NewCSISocketCheck(csiAddress string) HealthCheck {
+	return csiSocketCheck{csiAddress: csiAddress}
+}
+
+func (c csiSocketCheck) Name() string {
+	return "csi-socket"
+}
+
+func (c csiSocketCheck) Check(ctx context.Context) error {
+	conn, err := grpc.NewClient(c.csiAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("unable to create a client for %s: %w", c.csiAddress, err)
+	}
+	defer conn.Close()
+
+	if _, err := csi.NewIdentityClient(conn).GetPluginInfo(ctx, &csi.GetPluginInfoRequest{}); err != nil {
+		return fmt.Errorf("GetPluginInfo over %s failed: %w", c.csiAddress, err)
+	}
+	return nil
+}
+
+// binariesCheck verifies that the given executables are in PATH.
+type binariesCheck struct {
+	binaries []string
+}
+
+// NewBinariesCheck returns a check looking the given executables up in PATH.
+func NewBinariesCheck(binaries ...string) HealthCheck {
+	return binariesCheck{binaries: binaries}
+}
+
+func (c binariesCheck) Name() string {
+	return "binaries"
+}
+
+func (c binariesCheck) Check(_ context.Context) error {
+	var errs []error
+	for _, b := range c.binaries {
+		if _, err := exec.LookPath(b); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// devCheck verifies that the host's block devices are visible under dir. A node
+// plugin started without the host /dev mounted sees the container's own /dev
+// and reports every LV as "Device not found".
+type devCheck struct {
+	dir string
+}
+
+// NewDevCheck returns a check looking for at least one block device in dir.
+func NewDevCheck(dir string) HealthCheck {
+	return devCheck{dir: dir}
+}
+
+func (c devCheck) Name() string {
+	return "dev"
+}
+
+func (c devCheck) Check(_ context.Context) error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", c.dir, err)
+	}
+
+	for _, e := range entries {
+		if e.Type()&os.ModeDevice != 0 && e.Type()&os.ModeCharDevice == 0 {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no block devices are visible in %s", c.dir)
+}
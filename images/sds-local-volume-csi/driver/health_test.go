@@ -0,0 +1,93 @@
+/*
+Copyright 2025 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHealthCheck struct {
+	name string
+	err  error
+}
+
+func (c fakeHealthCheck) Name() string                  { return c.name }
+func (c fakeHealthCheck) Check(_ context.Context) error { return c.err }
+
+func TestHealthChecker(t *testing.T) {
+	t.Run("all_checks_pass", func(t *testing.T) {
+		hc := NewHealthChecker(fakeHealthCheck{name: "a"}, fakeHealthCheck{name: "b"})
+
+		rec := httptest.NewRecorder()
+		hc.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var report HealthReport
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.True(t, report.OK)
+		assert.Len(t, report.Checks, 2)
+		assert.Empty(t, report.Failed())
+	})
+
+	t.Run("a_failing_check_does_not_hide_the_others", func(t *testing.T) {
+		hc := NewHealthChecker(
+			fakeHealthCheck{name: "kube-api", err: errors.New("connection refused")},
+			fakeHealthCheck{name: "dev"},
+		)
+
+		rec := httptest.NewRecorder()
+		hc.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+		var report HealthReport
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.False(t, report.OK)
+		assert.Equal(t, []HealthCheckResult{
+			{Name: "kube-api", OK: false, Error: "connection refused"},
+			{Name: "dev", OK: true},
+		}, report.Checks)
+	})
+}
+
+func TestDevCheck(t *testing.T) {
+	t.Run("a_directory_without_block_devices_fails", func(t *testing.T) {
+		err := NewDevCheck(t.TempDir()).Check(context.Background())
+		assert.ErrorContains(t, err, "no block devices are visible")
+	})
+
+	t.Run("a_missing_directory_fails", func(t *testing.T) {
+		err := NewDevCheck("/nonexistent").Check(context.Background())
+		assert.ErrorContains(t, err, "unable to read /nonexistent")
+	})
+}
+
+func TestBinariesCheck(t *testing.T) {
+	err := NewBinariesCheck("sh", "definitely-not-a-binary").Check(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "definitely-not-a-binary")
+	assert.NotContains(t, err.Error(), `"sh"`)
+}
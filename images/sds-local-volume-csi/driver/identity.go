@@ -31,7 +31,7 @@ func (d *Driver) GetPluginInfo(_ context.Context, _ *csi.GetPluginInfoRequest) (
 		VendorVersion: version,
 	}
 
-	d.log.Named("GetPluginInfo").Info("called", "response", fmt.Sprintf("%+v", resp))
+	d.log.Named("GetPluginInfo").Debug("called", "response", fmt.Sprintf("%+v", resp))
 	return resp, nil
 }
 
@@ -74,15 +74,30 @@ func (d *Driver) GetPluginCapabilities(_ context.Context, _ *csi.GetPluginCapabi
 	return resp, nil
 }
 
-// Probe returns the health and readiness of the plugin
-func (d *Driver) Probe(_ context.Context, _ *csi.ProbeRequest) (*csi.ProbeResponse, error) {
-	d.log.Named("Probe").Info("called")
+// Probe returns the health and readiness of the plugin.
+//
+// The plugin reports ready only once it serves and while its readiness checks
+// pass, so a node plugin that cannot reach the API server or lacks mkfs is
+// visible to the sidecars instead of failing the first volume sent to it.
+func (d *Driver) Probe(ctx context.Context, _ *csi.ProbeRequest) (*csi.ProbeResponse, error) {
+	log := d.log.Named("Probe")
+	log.Info("called")
+
 	d.readyMu.Lock()
-	defer d.readyMu.Unlock()
+	ready := d.ready
+	d.readyMu.Unlock()
+
+	if ready {
+		report := d.probeHealth.Check(ctx)
+		if !report.OK {
+			log.Warn("the probe checks failed", "failed", fmt.Sprintf("%+v", report.Failed()))
+			ready = false
+		}
+	}
 
 	return &csi.ProbeResponse{
 		Ready: &wrappers.BoolValue{
-			Value: d.ready,
+			Value: ready,
 		},
 	}, nil
 }
@@ -0,0 +1,241 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/events"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+	snc "github.com/deckhouse/sds-node-configurator/api/v1alpha1"
+)
+
+// volDataFile is what the kubelet records of a CSI volume next to its
+// staging and publish mounts.
+const volDataFile = "vol_data.json"
+
+// volData is the part of volDataFile the janitor reads.
+type volData struct {
+	VolumeHandle string `json:"volumeHandle"`
+	DriverName   string `json:"driverName"`
+}
+
+// mountState is what the janitor makes of a mount of a volume of the driver.
+type mountState int
+
+const (
+	// mountInUse is a mount of an existing volume the kubelet knows about.
+	mountInUse mountState = iota
+	// mountOrphaned is a mount of a volume whose LVMLogicalVolume and
+	// PersistentVolume are both gone. It only keeps the LV from being removed.
+	mountOrphaned
+	// mountUnknown is a mount of an existing volume the kubelet has no record
+	// of. A pod may still use it, so it is reported and left alone.
+	mountUnknown
+)
+
+// classifyMount tells the state of a mount from whether the LVMLogicalVolume
+// and the PersistentVolume of its volume exist and whether the kubelet has a
+// record of the mount.
+func classifyMount(llvExists, pvExists, recorded bool) mountState {
+	switch {
+	case !llvExists && !pvExists:
+		return mountOrphaned
+	case !recorded:
+		return mountUnknown
+	default:
+		return mountInUse
+	}
+}
+
+// cleanStaleMounts looks for the stale mounts of the driver every
+// staleMountsInterval until ctx is cancelled. A crashed kubelet or a pod
+// deleted with --force can leave mounts behind, which keep
+// sds-node-configurator from removing their LVs.
+func (d *Driver) cleanStaleMounts(ctx context.Context) {
+	log := d.log.Named("cleanStaleMounts")
+
+	ticker := time.NewTicker(staleMountsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		d.sweepStaleMounts(ctx, log)
+	}
+}
+
+// sweepStaleMounts unmounts the orphaned mounts of the driver under the
+// kubelet directory and reports the ones of unknown use.
+func (d *Driver) sweepStaleMounts(ctx context.Context, log logger.Logger) {
+	mounts, err := d.storeManager.ListMounts()
+	if err != nil {
+		log.Error("unable to list the mounts", logger.Err(err))
+		return
+	}
+
+	// The mounts in the pod directories are bind mounts of the staging ones,
+	// so they go first.
+	var paths []string
+	for _, mount := range mounts {
+		if d.kubeletCSIPath(mount.Path) {
+			paths = append(paths, filepath.Clean(mount.Path))
+		}
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return d.podPath(paths[i]) && !d.podPath(paths[j])
+	})
+
+	unknown := 0
+	for _, path := range paths {
+		volumeID, recorded := d.mountVolume(path)
+		if volumeID == "" {
+			continue
+		}
+		log := log.With("volumeID", volumeID, "path", path)
+
+		llvExists, err := d.exists(ctx, client.ObjectKey{Name: volumeID}, &snc.LVMLogicalVolume{})
+		if err != nil {
+			log.Warn("unable to get the LVMLogicalVolume of a mount", logger.Err(err))
+			continue
+		}
+		pvExists, err := d.exists(ctx, client.ObjectKey{Name: volumeID}, &corev1.PersistentVolume{})
+		if err != nil {
+			log.Warn("unable to get the PersistentVolume of a mount", logger.Err(err))
+			continue
+		}
+
+		switch classifyMount(llvExists, pvExists, recorded) {
+		case mountOrphaned:
+			d.cleanMount(log, volumeID, path)
+		case mountUnknown:
+			unknown++
+			log.Warn("the kubelet has no record of the mount")
+			d.events.Warning(ctx, events.Target{PVName: volumeID}, events.ReasonStaleMount, "Mount %s of the volume on node %s is not known to the kubelet. Unmount it if no pod uses the volume", path, d.hostID)
+		}
+	}
+
+	d.metrics.SetStaleMounts(unknown)
+}
+
+// cleanMount unmounts an orphaned mount of a volume and removes its mount
+// point, and closes the LUKS device of an encrypted volume once it is not
+// mounted anymore.
+func (d *Driver) cleanMount(log logger.Logger, volumeID, path string) {
+	if !d.inFlight.InsertOperation(volumeID, "CleanStaleMount", "") {
+		log.Debug("the volume has an operation in flight, leaving the mount to the next sweep")
+		return
+	}
+	defer d.inFlight.Delete(volumeID)
+
+	if err := d.storeManager.Unstage(path); err != nil {
+		log.Error("unable to unmount an orphaned mount", logger.Err(err))
+		return
+	}
+	d.metrics.AddStaleMountCleaned()
+	log.Info("unmounted an orphaned mount")
+
+	if _, ok := d.published.Get(path); ok {
+		if err := d.published.Delete(path); err != nil {
+			log.Warn("unable to forget the published volume", logger.Err(err))
+		}
+	}
+	if volume, ok := d.staged.Get(volumeID); ok && filepath.Clean(volume.StagingPath) == path {
+		if err := d.staged.Delete(volumeID); err != nil {
+			log.Warn("unable to forget the staged volume", logger.Err(err))
+		}
+	}
+
+	if d.podPath(path) {
+		return
+	}
+	open, err := d.storeManager.PathExists(utils.LUKSMapperPath(volumeID))
+	if err != nil || !open {
+		return
+	}
+	if err := d.storeManager.CloseLUKS(volumeID); err != nil {
+		log.Warn("unable to close the LUKS device of an orphaned mount", logger.Err(err))
+	}
+}
+
+// mountVolume returns the volume of the driver mounted at path, and whether the
+// kubelet has a record of the mount. A mount the kubelet has no record of is
+// the driver's if the driver staged or published the volume there. An empty
+// volume ID is a mount of another driver, or one of unknown origin.
+func (d *Driver) mountVolume(path string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(path), volDataFile))
+	if err == nil {
+		var record volData
+		if err := json.Unmarshal(data, &record); err == nil && record.VolumeHandle != "" {
+			if record.DriverName != d.name {
+				return "", false
+			}
+			return record.VolumeHandle, true
+		}
+	}
+
+	if volume, ok := d.published.Get(path); ok {
+		return volume.VolumeID, false
+	}
+	for volumeID, volume := range d.staged.List() {
+		if filepath.Clean(volume.StagingPath) == path {
+			return volumeID, false
+		}
+	}
+
+	return "", false
+}
+
+// kubeletCSIPath tells whether path is where the kubelet has the CSI
+// volumes staged or published.
+func (d *Driver) kubeletCSIPath(path string) bool {
+	return strings.HasPrefix(path, filepath.Join(d.kubeletDir, "plugins", "kubernetes.io", "csi")+"/") || d.podPath(path)
+}
+
+// podPath tells whether path is where the kubelet has a CSI volume published
+// to a pod.
+func (d *Driver) podPath(path string) bool {
+	rest, ok := strings.CutPrefix(path, filepath.Join(d.kubeletDir, "pods")+"/")
+	return ok && (strings.Contains(rest, "/volumes/kubernetes.io~csi/") || strings.Contains(rest, "/volumeDevices/kubernetes.io~csi/"))
+}
+
+// exists tells whether the object named key exists.
+func (d *Driver) exists(ctx context.Context, key client.ObjectKey, obj client.Object) (bool, error) {
+	err := d.cl.Get(ctx, key, obj)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
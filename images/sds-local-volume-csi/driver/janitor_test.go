@@ -0,0 +1,87 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+)
+
+func TestClassifyMount(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		llvExists bool
+		pvExists  bool
+		recorded  bool
+		want      mountState
+	}{
+		{name: "in_use", llvExists: true, pvExists: true, recorded: true, want: mountInUse},
+		{name: "deleted", recorded: true, want: mountOrphaned},
+		{name: "deleted_unrecorded", want: mountOrphaned},
+		{name: "pv_left", pvExists: true, recorded: true, want: mountInUse},
+		{name: "unrecorded", llvExists: true, pvExists: true, want: mountUnknown},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, classifyMount(tc.llvExists, tc.pvExists, tc.recorded))
+		})
+	}
+}
+
+func TestMountVolume(t *testing.T) {
+	kubeletDir := t.TempDir()
+	d := &Driver{
+		name:       DefaultDriverName,
+		kubeletDir: kubeletDir,
+		published:  internal.NewPublishedVolumes(filepath.Join(t.TempDir(), publishedVolumesFile)),
+		staged:     internal.NewStagedVolumes(filepath.Join(t.TempDir(), stagedVolumesFile)),
+	}
+
+	record := func(path, data string) string {
+		require.NoError(t, os.MkdirAll(path, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), volDataFile), []byte(data), 0644))
+		return path
+	}
+
+	staging := record(filepath.Join(kubeletDir, "plugins/kubernetes.io/csi", DefaultDriverName, "0123abcd/globalmount"),
+		`{"volumeHandle":"pvc-1","driverName":"`+DefaultDriverName+`"}`)
+	volumeID, recorded := d.mountVolume(staging)
+	assert.Equal(t, "pvc-1", volumeID)
+	assert.True(t, recorded)
+	assert.True(t, d.kubeletCSIPath(staging))
+	assert.False(t, d.podPath(staging))
+
+	other := record(filepath.Join(kubeletDir, "pods/uid-1/volumes/kubernetes.io~csi/pvc-2/mount"),
+		`{"volumeHandle":"pvc-2","driverName":"other.csi.example.com"}`)
+	volumeID, _ = d.mountVolume(other)
+	assert.Empty(t, volumeID)
+	assert.True(t, d.podPath(other))
+
+	// The kubelet lost its record, the driver still has its own.
+	unrecorded := filepath.Join(kubeletDir, "pods/uid-2/volumes/kubernetes.io~csi/pvc-3/mount")
+	require.NoError(t, d.published.Set(unrecorded, internal.PublishedVolume{VolumeID: "pvc-3"}))
+	volumeID, recorded = d.mountVolume(unrecorded)
+	assert.Equal(t, "pvc-3", volumeID)
+	assert.False(t, recorded)
+
+	assert.False(t, d.kubeletCSIPath("/mnt/data"))
+}
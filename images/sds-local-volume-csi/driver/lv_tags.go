@@ -0,0 +1,73 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+)
+
+// syncLVTags applies the LV tags of the volumes staged on the node, whether a
+// pod uses them or not: the ones of the annotation ControllerModifyVolume has
+// put on the LVMLogicalVolume, or else those of the volume context. LV tags
+// are only managed for a volume with LVTagsKey set, so that tags added by hand
+// to other volumes stay. The snapshots mounted read-only are left as they are.
+func (d *Driver) syncLVTags(ctx context.Context, log logger.Logger) {
+	for volumeID, volume := range d.staged.List() {
+		if volume.ReadOnly {
+			continue
+		}
+
+		if err := d.syncVolumeLVTags(ctx, log.With("volumeID", volumeID), volumeID, volume); err != nil {
+			log.Warn("unable to apply the LV tags of a volume", logger.Err(err), slog.String("volumeID", volumeID))
+		}
+	}
+}
+
+func (d *Driver) syncVolumeLVTags(ctx context.Context, log logger.Logger, volumeID string, volume internal.StagedVolume) error {
+	value, ok := volume.Parameters[internal.LVTagsKey]
+	llv, err := utils.GetLVMLogicalVolume(ctx, d.cl, volumeID, "")
+	if err != nil {
+		log.Warn("unable to get the LVMLogicalVolume, applying the LV tags of the volume context", logger.Err(err))
+	} else if annotation, annotated := llv.Annotations[internal.LVTagsKey]; annotated {
+		value, ok = annotation, true
+	}
+	if !ok {
+		return nil
+	}
+
+	tags, err := utils.ParseLVTags(value)
+	if err != nil {
+		return err
+	}
+
+	// The LV is looked up by its UUID, which follows it through a rename.
+	lvPath := volume.LVPath
+	if volume.LVUUID != "" {
+		lvPath, err = d.storeManager.LVDevicePath(volume.LVUUID, "", "")
+		if err != nil {
+			return fmt.Errorf("unable to find the LV %s: %w", volume.LVUUID, err)
+		}
+	}
+
+	return d.storeManager.SyncLVTags(lvPath, tags)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+	"github.com/deckhouse/sds-node-configurator/api/v1alpha1"
+)
+
+// lvTagsStore finds the LVs under /dev/vg-renamed by their UUID and records
+// the tags it is asked to set, by LV path.
+type lvTagsStore struct {
+	utils.NodeStoreManager
+	tags map[string][]string
+}
+
+func (s *lvTagsStore) LVDevicePath(lvUUID, _, _ string) (string, error) {
+	return "/dev/vg-renamed/" + lvUUID, nil
+}
+
+func (s *lvTagsStore) SyncLVTags(lvPath string, tags []string) error {
+	s.tags[lvPath] = tags
+	return nil
+}
+
+// The tags of every staged volume are applied, published or not: the
+// annotation of the LVMLogicalVolume first, then the volume context.
+func TestSyncLVTags(t *testing.T) {
+	llv := func(name string, annotations map[string]string) *v1alpha1.LVMLogicalVolume {
+		return &v1alpha1.LVMLogicalVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+			Spec:       v1alpha1.LVMLogicalVolumeSpec{ActualLVNameOnTheNode: name, LVMVolumeGroupName: "lvg-1"},
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		llv("modified", map[string]string{internal.LVTagsKey: "tier:gold"}),
+		llv("created", nil),
+		llv("untagged", nil),
+	).Build()
+	store := &lvTagsStore{tags: map[string][]string{}}
+	d := &Driver{
+		cl:           cl,
+		storeManager: store,
+		staged:       internal.NewStagedVolumes(filepath.Join(t.TempDir(), stagedVolumesFile)),
+	}
+	for volumeID, volume := range map[string]internal.StagedVolume{
+		"modified": {LVPath: "/dev/vg-1/modified", LVUUID: "uuid-modified", Parameters: map[string]string{internal.LVTagsKey: "tier:silver"}},
+		"created":  {LVPath: "/dev/vg-1/created", LVUUID: "uuid-created", Parameters: map[string]string{internal.LVTagsKey: "tier:silver"}},
+		"no-uuid":  {LVPath: "/dev/vg-1/no-uuid", Parameters: map[string]string{internal.LVTagsKey: "tier:bronze"}},
+		"untagged": {LVPath: "/dev/vg-1/untagged", LVUUID: "uuid-untagged"},
+		"snapshot": {LVPath: "/dev/vg-1/snapshot", ReadOnly: true, Parameters: map[string]string{internal.LVTagsKey: "tier:gold"}},
+	} {
+		require.NoError(t, d.staged.Set(volumeID, volume))
+	}
+
+	d.syncLVTags(context.Background(), logger.NewNop())
+
+	assert.Equal(t, map[string][]string{
+		"/dev/vg-renamed/uuid-modified": {"tier:gold"},
+		"/dev/vg-renamed/uuid-created":  {"tier:silver"},
+		"/dev/vg-1/no-uuid":             {"tier:bronze"},
+	}, store.tags)
+}
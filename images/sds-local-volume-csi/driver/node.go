@@ -18,22 +18,33 @@ package driver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
 	"slices"
-	"strconv"
 	"strings"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sys/unix"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	mountutils "k8s.io/mount-utils"
 
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/encryption"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/events"
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/qos"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/tracing"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+	"github.com/deckhouse/sds-local-volume/lib/go/common/pkg/fsoptions"
 )
 
 const (
@@ -52,17 +63,21 @@ var (
 		csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
 		csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
 		csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
+		csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
 	}
 
 	ValidFSTypes = map[string]struct{}{
-		internal.FSTypeExt4: {},
-		internal.FSTypeXfs:  {},
+		internal.FSTypeExt4:  {},
+		internal.FSTypeExt3:  {},
+		internal.FSTypeXfs:   {},
+		internal.FSTypeBtrfs: {},
 	}
 )
 
-func (d *Driver) NodeStageVolume(_ context.Context, request *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
+func (d *Driver) NodeStageVolume(ctx context.Context, request *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
+	traceID := tracing.TraceID(ctx)
 	volumeID := request.GetVolumeId()
-	log := d.log.Named("NodeStageVolume").With("volumeID", volumeID)
+	log := d.log.Named("NodeStageVolume").With("traceID", traceID, "volumeID", volumeID)
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "[NodeStageVolume] Volume id cannot be empty")
 	}
@@ -83,8 +98,55 @@ func (d *Driver) NodeStageVolume(_ context.Context, request *csi.NodeStageVolume
 		return nil, status.Error(codes.InvalidArgument, "[NodeStageVolume] Volume group name cannot be empty")
 	}
 
+	encrypted := context[encryption.ModeKey] != ""
+	snapshot := context[internal.SnapshotKey] != ""
+
 	if volCap.GetBlock() != nil {
-		log.Info("block volume detected, skipping staging")
+		if !encrypted {
+			log.Info("block volume detected, skipping staging")
+			if snapshot {
+				// The snapshot must not be activated while NodeUnstageVolume
+				// deactivates it.
+				log.Debug("volume operation started")
+				if !d.inFlight.InsertOperation(volumeID, "NodeStageVolume", traceID) {
+					return nil, d.inFlightAbortedErr(volumeID)
+				}
+				defer func() {
+					log.Debug("volume operation completed")
+					d.inFlight.Delete(volumeID)
+				}()
+
+				if err := d.activateSnapshot(ctx, log, context, vgName, volumeID); err != nil {
+					return nil, err
+				}
+			}
+			devPath := d.devicePath(log, context, vgName, volumeID)
+			d.recordStagedVolume(ctx, log, context, volumeID, internal.StagedVolume{StagingPath: target, Block: true, Device: devPath, LVPath: devPath, ReadOnly: snapshot})
+			return &csi.NodeStageVolumeResponse{}, nil
+		}
+
+		// An encrypted block volume is staged by opening it: the pod gets
+		// the plain device, which NodePublishVolume looks up.
+		log.Debug("volume operation started")
+		if !d.inFlight.InsertOperation(volumeID, "NodeStageVolume", traceID) {
+			return nil, d.inFlightAbortedErr(volumeID)
+		}
+		defer func() {
+			log.Debug("volume operation completed")
+			d.inFlight.Delete(volumeID)
+		}()
+
+		devPath, err := d.stagingDevice(ctx, log, context, vgName, volumeID)
+		if err != nil {
+			return nil, err
+		}
+		mapperPath, err := d.openEncrypted(ctx, log, devPath, volumeID, request.GetSecrets())
+		if err != nil {
+			return nil, err
+		}
+
+		d.recordStagedVolume(ctx, log, context, volumeID, internal.StagedVolume{StagingPath: target, Block: true, Device: mapperPath, LVPath: devPath})
+		log.Info("encrypted block volume staged successfully", "devicePath", devPath, "mapperPath", mapperPath)
 		return &csi.NodeStageVolumeResponse{}, nil
 	}
 
@@ -106,36 +168,70 @@ func (d *Driver) NodeStageVolume(_ context.Context, request *csi.NodeStageVolume
 
 	formatOptions := []string{}
 
-	// support mounting on old linux kernels
-	needLegacySupport, err := needLegacyXFSSupport()
-	if err != nil {
-		return nil, err
+	// The mkfs options of the class are checked again: they are run as root,
+	// and a StorageClass can be written by hand.
+	var classArgs []string
+	if options := context[internal.MkfsOptionsKey]; options != "" {
+		var err error
+		classArgs, err = fsoptions.MkfsArgs(fsType, options)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "[NodeStageVolume] Invalid mkfs options of volume %q: %v", volumeID, err)
+		}
 	}
-	if fsType == internal.FSTypeXfs && needLegacySupport {
-		log.Info("legacy xfs support is on")
-		formatOptions = append(formatOptions, "-m", "bigtime=0,inobtcount=0,reflink=0", "-i", "nrext64=0")
+
+	// A filesystem must not get features the kernel of the node cannot mount,
+	// which a recent mkfs.xfs turns on by default.
+	if fsType == internal.FSTypeXfs {
+		featureArgs, err := xfsFormatOptions(d.xfsSupport, classArgs)
+		if err != nil {
+			return nil, status.Errorf(codes.FailedPrecondition, "[NodeStageVolume] Unable to format volume %q on node %s: %v", volumeID, d.hostID, err)
+		}
+		formatOptions = append(formatOptions, featureArgs...)
 	}
+	formatOptions = append(formatOptions, classArgs...)
 
 	mountOptions := collectMountOptions(fsType, mountVolume.GetMountFlags(), []string{})
+	// dm-crypt drops the discards of an encrypted volume, which is opened
+	// without --allow-discards.
+	if context[internal.DiscardKey] == internal.DiscardOnline && context[internal.LvmTypeKey] == internal.LVMTypeThin && !encrypted && !snapshot {
+		mountOptions = collectMountOptions(fsType, []string{"discard"}, mountOptions)
+	}
+	if snapshot {
+		snapshotOptions, err := snapshotMountOptions(strings.ToLower(fsType))
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "[NodeStageVolume] Unable to mount volume %q: %v", volumeID, err)
+		}
+		mountOptions = collectMountOptions(fsType, snapshotOptions, mountOptions)
+	}
 
 	log.Debug("volume operation started")
-	ok = d.inFlight.Insert(volumeID)
+	ok = d.inFlight.InsertOperation(volumeID, "NodeStageVolume", traceID)
 	if !ok {
-		return nil, status.Errorf(codes.Aborted, VolumeOperationAlreadyExists, volumeID)
+		return nil, d.inFlightAbortedErr(volumeID)
 	}
 	defer func() {
 		log.Debug("volume operation completed")
 		d.inFlight.Delete(volumeID)
 	}()
 
-	devPath := fmt.Sprintf("/dev/%s/%s", vgName, request.VolumeId)
-	log.Debug("checking whether the device exists", "devicePath", devPath)
-	exists, err := d.storeManager.PathExists(devPath)
+	if snapshot {
+		if err := d.activateSnapshot(ctx, log, context, vgName, volumeID); err != nil {
+			return nil, err
+		}
+	}
+
+	devPath, err := d.stagingDevice(ctx, log, context, vgName, volumeID)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "[NodeStageVolume] Error checking if device exists: %v", err)
+		return nil, err
 	}
-	if !exists {
-		return nil, status.Errorf(codes.NotFound, "[NodeStageVolume] Device %s not found", devPath)
+
+	// The filesystem goes onto the plain device of an encrypted volume.
+	lvPath := devPath
+	if encrypted {
+		devPath, err = d.openEncrypted(ctx, log, devPath, volumeID, request.GetSecrets())
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	lvmType := context[internal.LvmTypeKey]
@@ -147,34 +243,57 @@ func (d *Driver) NodeStageVolume(_ context.Context, request *csi.NodeStageVolume
 	log.Trace("resolved thin pool", "thinPoolName", lvmThinPoolName)
 	log.Trace("resolved filesystem type", "fsType", fsType)
 
-	err = d.storeManager.NodeStageVolumeFS(devPath, target, fsType, mountOptions, formatOptions, lvmType, lvmThinPoolName)
+	// A snapshot is mounted as it is, neither checked nor formatted.
+	checked := snapshot
+	if !snapshot {
+		checked, err = d.checkFilesystem(ctx, log, context, devPath, target, fsType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// mkfs and the mount run inside one mount-utils call, so they share a span.
+	_, span := tracing.Start(ctx, "FormatAndMount", attribute.String("device", devPath), attribute.String("fs.type", fsType))
+	err = d.storeManager.NodeStageVolumeFS(devPath, target, fsType, mountOptions, formatOptions, lvmType, lvmThinPoolName, checked)
+	tracing.End(span, err)
 	if err != nil {
 		log.Error("unable to mount the volume", logger.Err(err), slog.String("devicePath", devPath), slog.String("target", target))
+		var mountErr mountutils.MountError
+		if errors.As(err, &mountErr) && mountErr.Type == mountutils.FormatFailed {
+			d.events.Warning(ctx, events.TargetFromParameters(context), events.ReasonFormatFailed, "Unable to format device %s as %s on node %s: %v", devPath, fsType, d.hostID, err)
+		}
 		return nil, status.Errorf(codes.Internal, "[NodeStageVolume] Error format device %q and mounting volume at %q: %v", devPath, target, err)
 	}
 
-	needResize, err := d.storeManager.NeedResize(devPath, target)
-	if err != nil {
-		log.Error("unable to check whether the volume needs resizing", logger.Err(err), slog.String("devicePath", devPath), slog.String("target", target))
-		return nil, status.Errorf(codes.Internal, "[NodeStageVolume] Error checking if the volume %q (%q) mounted at %q needs resizing: %v", volumeID, devPath, target, err)
+	needResize := false
+	if !snapshot {
+		needResize, err = d.storeManager.NeedResize(devPath, target)
+		if err != nil {
+			log.Error("unable to check whether the volume needs resizing", logger.Err(err), slog.String("devicePath", devPath), slog.String("target", target))
+			return nil, status.Errorf(codes.Internal, "[NodeStageVolume] Error checking if the volume %q (%q) mounted at %q needs resizing: %v", volumeID, devPath, target, err)
+		}
 	}
 
 	if needResize {
 		log.Info("resizing the volume", "devicePath", devPath, "target", target)
+		_, span := tracing.Start(ctx, "ResizeFS", attribute.String("device", devPath))
 		err = d.storeManager.ResizeFS(target)
+		tracing.End(span, err)
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, "[NodeStageVolume] Error resizing volume %q (%q) mounted at %q: %v", volumeID, devPath, target, err)
 		}
 	}
 
+	d.recordStagedVolume(ctx, log, context, volumeID, internal.StagedVolume{StagingPath: target, Device: devPath, LVPath: lvPath, ReadOnly: snapshot})
 	log.Info("volume staged successfully", "devicePath", devPath, "target", target, "fsType", fsType)
 
 	return &csi.NodeStageVolumeResponse{}, nil
 }
 
-func (d *Driver) NodeUnstageVolume(_ context.Context, request *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
+func (d *Driver) NodeUnstageVolume(ctx context.Context, request *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
+	traceID := tracing.TraceID(ctx)
 	volumeID := request.GetVolumeId()
-	log := d.log.Named("NodeUnstageVolume").With("volumeID", volumeID)
+	log := d.log.Named("NodeUnstageVolume").With("traceID", traceID, "volumeID", volumeID)
 	log.Debug("called", "request", fmt.Sprintf("%v", request))
 	if len(volumeID) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "[NodeUnstageVolume] Volume id cannot be empty")
@@ -186,24 +305,45 @@ func (d *Driver) NodeUnstageVolume(_ context.Context, request *csi.NodeUnstageVo
 	}
 
 	log.Debug("volume operation started")
-	ok := d.inFlight.Insert(volumeID)
+	ok := d.inFlight.InsertOperation(volumeID, "NodeUnstageVolume", traceID)
 	if !ok {
-		return nil, status.Errorf(codes.Aborted, VolumeOperationAlreadyExists, volumeID)
+		return nil, d.inFlightAbortedErr(volumeID)
 	}
 	defer func() {
 		log.Debug("volume operation completed")
 		d.inFlight.Delete(volumeID)
 	}()
-	err := d.storeManager.Unstage(target)
+	err := d.unmount(ctx, log, "NodeUnstageVolume", volumeID, target)
+	if err != nil {
+		return nil, err
+	}
+
+	// NodeUnstageVolume has no volume context to tell an encrypted volume by,
+	// so the LUKS device is closed if there is one.
+	err = d.storeManager.CloseLUKS(volumeID)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "[NodeUnstageVolume] Error unmounting volume %q mounted at %q: %v", volumeID, target, err)
+		log.Error("unable to close the LUKS device", logger.Err(err))
+		return nil, status.Errorf(codes.Internal, "[NodeUnstageVolume] Error closing the LUKS device of volume %q: %v", volumeID, err)
 	}
 
+	// The thin snapshot of a snapshot volume was activated by NodeStageVolume
+	// and is left inactive again.
+	if volume, ok := d.staged.Get(volumeID); ok && volume.ReadOnly {
+		err = d.storeManager.DeactivateReadOnly(volume.LVPath)
+		if err != nil {
+			log.Error("unable to deactivate the snapshot", logger.Err(err), slog.String("lvPath", volume.LVPath))
+			return nil, status.Errorf(codes.Internal, "[NodeUnstageVolume] Error deactivating snapshot %q of volume %q: %v", volume.LVPath, volumeID, err)
+		}
+	}
+
+	d.forgetStagedVolume(log, volumeID)
+
 	return &csi.NodeUnstageVolumeResponse{}, nil
 }
 
-func (d *Driver) NodePublishVolume(_ context.Context, request *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
-	log := d.log.Named("NodePublishVolume").With("volumeID", request.GetVolumeId())
+func (d *Driver) NodePublishVolume(ctx context.Context, request *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
+	traceID := tracing.TraceID(ctx)
+	log := d.log.Named("NodePublishVolume").With("traceID", traceID, "volumeID", request.GetVolumeId())
 
 	log.Trace("start", "request", request.String())
 
@@ -227,8 +367,10 @@ func (d *Driver) NodePublishVolume(_ context.Context, request *csi.NodePublishVo
 		return nil, status.Error(codes.InvalidArgument, "[NodePublishVolume] Volume capability cannot be empty")
 	}
 
+	// A snapshot volume is read-only whatever the pod asks for.
+	readonly := request.GetReadonly() || request.GetVolumeContext()[internal.SnapshotKey] != ""
 	mountOptions := []string{"bind"}
-	if request.GetReadonly() {
+	if readonly {
 		mountOptions = append(mountOptions, "ro")
 	}
 
@@ -237,21 +379,36 @@ func (d *Driver) NodePublishVolume(_ context.Context, request *csi.NodePublishVo
 		return nil, status.Error(codes.InvalidArgument, "[NodePublishVolume] Volume group name cannot be empty")
 	}
 
-	devPath := fmt.Sprintf("/dev/%s/%s", vgName, request.VolumeId)
+	devPath := d.devicePath(log, request.GetVolumeContext(), vgName, volumeID)
 	log.Debug("checking whether the device exists", "devicePath", devPath)
 	exists, err := d.storeManager.PathExists(devPath)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "[NodePublishVolume] Error checking if device exists: %v", err)
 	}
 	if !exists {
+		d.events.Warning(ctx, events.TargetFromParameters(request.GetVolumeContext()), events.ReasonDeviceMissing, "Device %s of the volume is missing on node %s", devPath, d.hostID)
 		return nil, status.Errorf(codes.NotFound, "[NodePublishVolume] Device %q not found", devPath)
 	}
 
+	// The pod gets the plain device of an encrypted volume, which
+	// NodeStageVolume has opened.
+	lvPath := devPath
+	if request.GetVolumeContext()[encryption.ModeKey] != "" {
+		devPath = utils.LUKSMapperPath(volumeID)
+		open, err := d.storeManager.PathExists(devPath)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "[NodePublishVolume] Error checking if the LUKS device exists: %v", err)
+		}
+		if !open {
+			return nil, status.Errorf(codes.FailedPrecondition, "[NodePublishVolume] The LUKS device %q of the volume is not open, the volume is not staged", devPath)
+		}
+	}
+
 	log.Debug("volume operation started")
 
-	ok = d.inFlight.Insert(volumeID)
+	ok = d.inFlight.InsertOperation(volumeID, "NodePublishVolume", traceID)
 	if !ok {
-		return nil, status.Errorf(codes.Aborted, VolumeOperationAlreadyExists, volumeID)
+		return nil, d.inFlightAbortedErr(volumeID)
 	}
 	defer func() {
 		log.Debug("volume operation completed")
@@ -262,7 +419,9 @@ func (d *Driver) NodePublishVolume(_ context.Context, request *csi.NodePublishVo
 	case *csi.VolumeCapability_Block:
 		log.Trace("block volume detected")
 
+		_, span := tracing.Start(ctx, "Mount", attribute.String("device", devPath))
 		err := d.storeManager.NodePublishVolumeBlock(devPath, target, mountOptions)
+		tracing.End(span, err)
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, "[NodePublishVolume] Error mounting volume %q at %q: %v", devPath, target, err)
 		}
@@ -286,17 +445,123 @@ func (d *Driver) NodePublishVolume(_ context.Context, request *csi.NodePublishVo
 
 		mountOptions = collectMountOptions(fsType, mountVolume.GetMountFlags(), mountOptions)
 
+		_, span := tracing.Start(ctx, "Mount", attribute.String("device", devPath), attribute.String("fs.type", fsType))
 		err := d.storeManager.NodePublishVolumeFS(source, devPath, target, fsType, mountOptions)
+		tracing.End(span, err)
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, "[NodePublishVolume] Error bind mounting volume %q. Source: %q. Target: %q. Mount options:%v. Err: %v", volumeID, source, target, mountOptions, err)
 		}
 	}
 
+	if err := d.publishVolumeAttributes(ctx, log, request.GetVolumeContext(), volumeID, devPath, lvPath, target, readonly); err != nil {
+		return nil, err
+	}
+
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
-func (d *Driver) NodeUnpublishVolume(_ context.Context, request *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
-	log := d.log.Named("NodeUnpublishVolume").With("volumeID", request.GetVolumeId())
+// publishVolumeAttributes records a volume published to a pod and applies its
+// IO limits. Limits that cannot be applied do not fail the publication: the
+// pod runs without them, which is reported as an event, and
+// syncPublishedVolumes keeps trying. The LV tags do not depend on the pod and
+// are applied to the staged volumes by syncLVTags.
+func (d *Driver) publishVolumeAttributes(ctx context.Context, log logger.Logger, volumeContext map[string]string, volumeID, devPath, lvPath, target string, readonly bool) error {
+	podUID, ok := qos.PodUIDFromTargetPath(target)
+	if !ok {
+		log.Warn("unable to tell the pod from the target path, the IO limits are not applied", "targetPath", target)
+		return nil
+	}
+
+	volume := internal.PublishedVolume{
+		VolumeID:   volumeID,
+		PodUID:     podUID,
+		Device:     devPath,
+		LVPath:     lvPath,
+		Readonly:   readonly,
+		Parameters: mutableParameters(volumeContext),
+	}
+
+	if err := d.published.Set(target, volume); err != nil {
+		return status.Errorf(codes.Internal, "[NodePublishVolume] Unable to record the published volume: %v", err)
+	}
+
+	_, span := tracing.Start(ctx, "SyncVolumeAttributes", attribute.String("pod.uid", podUID))
+	err := d.syncVolumeAttributes(ctx, log, target, volume)
+	tracing.End(span, err)
+	if err != nil {
+		log.Error("unable to apply the IO limits", logger.Err(err), slog.String("podUID", podUID))
+		d.events.Warning(ctx, events.TargetFromParameters(volumeContext), events.ReasonVolumeAttributesNotApplied, "Unable to apply the IO limits of the volume on node %s: %v", d.hostID, err)
+	}
+
+	return nil
+}
+
+// mutableParameters returns the parameters of volumeContext a
+// VolumeAttributesClass may change, or nil if there are none.
+func mutableParameters(volumeContext map[string]string) map[string]string {
+	var params map[string]string
+	for _, key := range mutableParameterKeys {
+		if value, ok := volumeContext[key]; ok {
+			if params == nil {
+				params = make(map[string]string, len(mutableParameterKeys))
+			}
+			params[key] = value
+		}
+	}
+	return params
+}
+
+// syncVolumeAttributes applies the IO limits of a volume published at target:
+// those of the volume context, overridden by the annotations
+// ControllerModifyVolume has put on the LVMLogicalVolume. The limits applied
+// are recorded.
+func (d *Driver) syncVolumeAttributes(ctx context.Context, log logger.Logger, target string, volume internal.PublishedVolume) error {
+	params := maps.Clone(volume.Parameters)
+	if params == nil {
+		params = make(map[string]string, len(mutableParameterKeys))
+	}
+
+	llv, err := utils.GetLVMLogicalVolume(ctx, d.cl, volume.VolumeID, "")
+	if err != nil {
+		log.Warn("unable to get the LVMLogicalVolume, applying the attributes of the volume context", logger.Err(err))
+	} else {
+		for _, key := range mutableParameterKeys {
+			if value, ok := llv.Annotations[key]; ok {
+				params[key] = value
+			}
+		}
+	}
+
+	var errs []error
+	limits, err := qos.Limits{}.With(params)
+	switch {
+	case err != nil:
+		errs = append(errs, fmt.Errorf("IO limits: %w", err))
+	case limits == volume.Limits && limits.IsZero():
+		// Nothing to apply nor to clear, and no io controller is needed.
+	default:
+		err := qos.Apply(d.cgroupRoot, volume.PodUID, volume.Device, limits)
+		switch {
+		case errors.Is(err, qos.ErrPodCgroupNotFound):
+			// The pod is gone; NodeUnpublishVolume forgets the volume.
+			log.Debug("the pod of the volume has no cgroup", "podUID", volume.PodUID)
+		case err != nil:
+			errs = append(errs, fmt.Errorf("IO limits: %w", err))
+		case limits != volume.Limits:
+			log.Info("applied the IO limits", "podUID", volume.PodUID, "limits", limits.String())
+			volume.Limits = limits
+			if err := d.published.Set(target, volume); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (d *Driver) NodeUnpublishVolume(ctx context.Context, request *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
+	traceID := tracing.TraceID(ctx)
+	log := d.log.Named("NodeUnpublishVolume").With("traceID", traceID, "volumeID", request.GetVolumeId())
 	log.Debug("called", "request", fmt.Sprintf("%v", request))
 
 	log.Trace("start", "request", request.String())
@@ -312,23 +577,40 @@ func (d *Driver) NodeUnpublishVolume(_ context.Context, request *csi.NodeUnpubli
 	}
 
 	log.Debug("volume operation started")
-	ok := d.inFlight.Insert(volumeID)
+	ok := d.inFlight.InsertOperation(volumeID, "NodeUnpublishVolume", traceID)
 	if !ok {
-		return nil, status.Errorf(codes.Aborted, VolumeOperationAlreadyExists, volumeID)
+		return nil, d.inFlightAbortedErr(volumeID)
 	}
 	defer func() {
 		log.Debug("volume operation completed")
 		d.inFlight.Delete(volumeID)
 	}()
 
-	err := d.storeManager.Unpublish(target)
+	err := d.unmount(ctx, log, "NodeUnpublishVolume", volumeID, target)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "[NodeUnpublishVolume] Error unmounting volume %q mounted at %q: %v", volumeID, target, err)
+		return nil, err
+	}
+
+	if err := d.published.Delete(target); err != nil {
+		return nil, status.Errorf(codes.Internal, "[NodeUnpublishVolume] Unable to forget the published volume %q: %v", volumeID, err)
 	}
 
 	return &csi.NodeUnpublishVolumeResponse{}, nil
 }
 
+// inFlightAbortedErr returns the Aborted status for a volume that another
+// operation already holds, naming that operation so that it can be found in the
+// logs by its trace ID. The holder may finish between the failed insert and the
+// lookup, in which case the plain message is returned.
+func (d *Driver) inFlightAbortedErr(volumeID string) error {
+	op, ok := d.inFlight.Get(volumeID)
+	if !ok {
+		return status.Errorf(codes.Aborted, VolumeOperationAlreadyExists, volumeID)
+	}
+
+	return status.Errorf(codes.Aborted, VolumeOperationAlreadyExists+" by %s (traceID: %s, running for %s)", volumeID, op.RPC, op.TraceID, op.Age(time.Now()).Round(time.Second))
+}
+
 // IsBlock checks if the given path is a block device
 func (d *Driver) IsBlockDevice(fullPath string) (bool, error) {
 	var st unix.Stat_t
@@ -360,7 +642,8 @@ func (d *Driver) getBlockSizeBytes(devicePath string) (uint64, error) {
 }
 
 func (d *Driver) NodeGetVolumeStats(_ context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
-	d.log.Named("NodeGetVolumeStats").Info("called")
+	log := d.log.Named("NodeGetVolumeStats").With("volumeID", req.GetVolumeId(), "volumePath", req.GetVolumePath())
+	log.Info("called")
 
 	isBlock, err := d.IsBlockDevice(req.VolumePath)
 
@@ -373,50 +656,93 @@ func (d *Driver) NodeGetVolumeStats(_ context.Context, req *csi.NodeGetVolumeSta
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, "failed to get block capacity on path %s: %v", req.VolumePath, err)
 		}
+		usage := &csi.VolumeUsage{Unit: csi.VolumeUsage_BYTES, Total: int64(bcap)}
+		if volume, ok := d.published.Get(req.VolumePath); ok {
+			usage, _ = d.blockVolumeUsage(log, volume.LVPath, bcap)
+		}
 		return &csi.NodeGetVolumeStatsResponse{
-			Usage: []*csi.VolumeUsage{
-				{
-					Unit:  csi.VolumeUsage_BYTES,
-					Total: int64(bcap),
-				},
-			},
+			Usage:           []*csi.VolumeUsage{usage},
+			VolumeCondition: d.volumeCondition(log, req, true),
 		}, nil
 	}
 
 	// For filesystem mounts, get filesystem statistics
-	var fsStat syscall.Statfs_t
-	if err := syscall.Statfs(req.VolumePath, &fsStat); err != nil {
+	bytes, inodes, err := fsUsage(req.VolumePath)
+	if err != nil {
+		// A filesystem that shut itself down on an error, as XFS does, fails
+		// every call with EIO. That is the condition of the volume rather than
+		// a failure of the call.
+		if errors.Is(err, syscall.EIO) {
+			return &csi.NodeGetVolumeStatsResponse{
+				VolumeCondition: &csi.VolumeCondition{
+					Abnormal: true,
+					Message:  fmt.Sprintf("the filesystem at %s fails with an IO error, it has likely been shut down, see the kernel log of node %s", req.VolumePath, d.hostID),
+				},
+			}, nil
+		}
 		return nil, status.Errorf(codes.Internal, "failed to statfs %s: %v", req.VolumePath, err)
 	}
 
-	available := int64(fsStat.Bavail) * fsStat.Bsize
-	total := int64(fsStat.Blocks) * fsStat.Bsize
-	used := (int64(fsStat.Blocks) - int64(fsStat.Bfree)) * fsStat.Bsize
-
-	inodes := int64(fsStat.Files)
-	inodesFree := int64(fsStat.Ffree)
-	inodesUsed := inodes - inodesFree
-
 	return &csi.NodeGetVolumeStatsResponse{
-		Usage: []*csi.VolumeUsage{
-			{
-				Available: available,
-				Total:     total,
-				Used:      used,
-				Unit:      csi.VolumeUsage_BYTES,
-			},
-			{
-				Available: inodesFree,
-				Total:     inodes,
-				Used:      inodesUsed,
-				Unit:      csi.VolumeUsage_INODES,
-			},
-		},
+		Usage:           []*csi.VolumeUsage{bytes, inodes},
+		VolumeCondition: d.volumeCondition(log, req, false),
 	}, nil
 }
 
-func (d *Driver) NodeExpandVolume(_ context.Context, request *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
-	log := d.log.Named("NodeExpandVolume").With("volumeID", request.GetVolumeId())
+// fsUsage returns the usage of the filesystem mounted at path in bytes and in
+// inodes.
+func fsUsage(path string) (bytes, inodes *csi.VolumeUsage, err error) {
+	var fsStat syscall.Statfs_t
+	if err := syscall.Statfs(path, &fsStat); err != nil {
+		return nil, nil, err
+	}
+
+	bytes = &csi.VolumeUsage{
+		Available: int64(fsStat.Bavail) * fsStat.Bsize,
+		Total:     int64(fsStat.Blocks) * fsStat.Bsize,
+		Used:      (int64(fsStat.Blocks) - int64(fsStat.Bfree)) * fsStat.Bsize,
+		Unit:      csi.VolumeUsage_BYTES,
+	}
+	inodes = &csi.VolumeUsage{
+		Available: int64(fsStat.Ffree),
+		Total:     int64(fsStat.Files),
+		Used:      int64(fsStat.Files) - int64(fsStat.Ffree),
+		Unit:      csi.VolumeUsage_INODES,
+	}
+	return bytes, inodes, nil
+}
+
+// blockVolumeUsage returns the usage of a block volume of size bytes on the LV
+// at lvPath. Only a thin volume tells what it uses: the blocks it has mapped in
+// its pool. What it can still use is capped by the space left in the pool,
+// which the other volumes of the pool share. known is false when only the size
+// is known.
+func (d *Driver) blockVolumeUsage(log logger.Logger, lvPath string, size uint64) (usage *csi.VolumeUsage, known bool) {
+	usage = &csi.VolumeUsage{
+		Unit:  csi.VolumeUsage_BYTES,
+		Total: int64(size),
+	}
+
+	used, poolFree, thin, err := d.storeManager.ThinVolumeUsage(lvPath)
+	if err != nil {
+		log.Warn("unable to get the usage of the thin volume", logger.Err(err), "lvPath", lvPath)
+		return usage, false
+	}
+	if !thin {
+		return usage, false
+	}
+
+	// The LUKS header of an encrypted volume is mapped too, but not part of
+	// the size.
+	used = min(used, size)
+	usage.Used = int64(used)
+	usage.Available = int64(min(size-used, poolFree))
+	return usage, true
+}
+
+func (d *Driver) NodeExpandVolume(ctx context.Context, request *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
+	traceID := tracing.TraceID(ctx)
+	log := d.log.Named("NodeExpandVolume").With("traceID", traceID, "volumeID", request.GetVolumeId())
 
 	log.Trace("start", "request", request.String())
 
@@ -429,13 +755,231 @@ func (d *Driver) NodeExpandVolume(_ context.Context, request *csi.NodeExpandVolu
 		return nil, status.Error(codes.InvalidArgument, "Volume Path cannot be empty")
 	}
 
-	err := d.storeManager.ResizeFS(volumePath)
+	// The capability is optional in the request; a block volume is published
+	// as its device.
+	volCap := request.GetVolumeCapability()
+	block := volCap.GetBlock() != nil
+	if volCap == nil {
+		var err error
+		block, err = d.IsBlockDevice(volumePath)
+		if err != nil {
+			return nil, status.Errorf(codes.NotFound, "unable to stat the volume path %q: %v", volumePath, err)
+		}
+	}
+
+	if err := d.checkLVGrown(ctx, log, volumeID, volumePath, request.GetCapacityRange().GetRequiredBytes()); err != nil {
+		return nil, err
+	}
+
+	// An encrypted volume grows in two steps: the LUKS device over the grown
+	// LV first, the filesystem over the LUKS device then.
+	open, err := d.storeManager.PathExists(utils.LUKSMapperPath(volumeID))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to check whether the LUKS device exists: %v", err)
+	}
+	if open {
+		// An empty key file would fail cryptsetup as a wrong passphrase does,
+		// which reads as a fault of the node rather than of the request.
+		passphrase := request.GetSecrets()[encryption.SecretKey]
+		if passphrase == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "the volume is encrypted, but its node-expand Secret has no %q", encryption.SecretKey)
+		}
+
+		_, span := tracing.Start(ctx, "ResizeLUKS", attribute.String("volume.path", volumePath))
+		err = d.storeManager.ResizeLUKS(volumeID, []byte(passphrase))
+		tracing.End(span, err)
+		if err != nil {
+			log.Error("unable to resize the LUKS device", logger.Err(err))
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	if block {
+		return d.expandBlockVolume(log, request)
+	}
+
+	// The volume path of a volume that is only staged is not mounted; its
+	// staging path is, if the kubelet passes it.
+	mountedPath, err := d.mountedPath(volumePath, request.GetStagingTargetPath())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to check whether %q is mounted: %v", volumePath, err)
+	}
+
+	var capacity int64
+	if mountedPath != "" {
+		_, span := tracing.Start(ctx, "ResizeFS", attribute.String("volume.path", mountedPath))
+		err = d.storeManager.ResizeFS(mountedPath)
+		tracing.End(span, err)
+		if err != nil {
+			log.Error("unable to resize the filesystem", logger.Err(err))
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		bytes, _, err := fsUsage(mountedPath)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to statfs %s: %v", mountedPath, err)
+		}
+		capacity = bytes.Total
+	} else {
+		// The temporary mount must not race a NodeStageVolume of the volume.
+		if !d.inFlight.InsertOperation(volumeID, "NodeExpandVolume", traceID) {
+			return nil, d.inFlightAbortedErr(volumeID)
+		}
+		defer d.inFlight.Delete(volumeID)
+
+		capacity, err = d.expandUnmountedVolume(ctx, log, volumeID, open)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("the filesystem has grown", "capacityBytes", capacity)
+	return &csi.NodeExpandVolumeResponse{CapacityBytes: capacity}, nil
+}
+
+// mountedPath returns the first of the paths the filesystem of a volume is
+// mounted at, or "" if it is mounted at neither.
+func (d *Driver) mountedPath(paths ...string) (string, error) {
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		mount, err := d.storeManager.FindMount(path)
+		if err != nil {
+			return "", err
+		}
+		if mount != nil {
+			return path, nil
+		}
+	}
+
+	return "", nil
+}
+
+// expandUnmountedVolume grows the filesystem of a volume mounted nowhere,
+// through a temporary mount with the options of a staged volume. The device
+// is the one recorded when the volume was staged, the open LUKS device of an
+// encrypted volume, or else the LV of the volume on the node.
+func (d *Driver) expandUnmountedVolume(ctx context.Context, log logger.Logger, volumeID string, luksOpen bool) (int64, error) {
+	var devicePath string
+	switch volume, ok := d.staged.Get(volumeID); {
+	case ok:
+		devicePath = volume.Device
+	case luksOpen:
+		devicePath = utils.LUKSMapperPath(volumeID)
+	default:
+		lvPath, err := d.nodeLVPath(ctx, log, volumeID)
+		if err != nil {
+			return 0, status.Errorf(codes.FailedPrecondition, "volume %q is not mounted and its LV is not found on node %s: %v", volumeID, d.hostID, err)
+		}
+		devicePath = lvPath
+	}
+
+	fsType, err := d.storeManager.DiskFormat(devicePath)
+	if err != nil {
+		return 0, status.Errorf(codes.Internal, "unable to get the filesystem of device %q: %v", devicePath, err)
+	}
+	switch fsType {
+	case "":
+		return 0, status.Errorf(codes.FailedPrecondition, "device %q of volume %q has no filesystem", devicePath, volumeID)
+	case utils.LUKSFormat:
+		return 0, status.Errorf(codes.FailedPrecondition, "volume %q is encrypted and not open on node %s, it grows once staged", volumeID, d.hostID)
+	}
+
+	log.Info("the volume is not mounted, resizing its filesystem offline", "devicePath", devicePath, "fsType", fsType)
+	_, span := tracing.Start(ctx, "ResizeFSOffline", attribute.String("device", devicePath))
+	capacity, err := d.storeManager.ResizeFSOffline(devicePath, fsType, filepath.Join(d.pluginDir, resizeDir), collectMountOptions(fsType, nil, nil))
+	tracing.End(span, err)
+	if err != nil {
+		log.Error("unable to resize the filesystem offline", logger.Err(err), slog.String("devicePath", devicePath))
+		return 0, status.Error(codes.Internal, err.Error())
+	}
+
+	return capacity, nil
+}
+
+// nodeLVPath returns the path of the LV of a volume on the node, resolved by
+// devicePath from its LVMLogicalVolume and the LVMVolumeGroup of that, which
+// must be on the node.
+func (d *Driver) nodeLVPath(ctx context.Context, log logger.Logger, volumeID string) (string, error) {
+	llv, err := utils.GetLVMLogicalVolume(ctx, d.cl, volumeID, "")
+	if err != nil {
+		return "", err
+	}
+	lvg, err := utils.GetLVMVolumeGroup(ctx, d.cl, llv.Spec.LVMVolumeGroupName)
+	if err != nil {
+		return "", err
+	}
+	if lvg.Spec.Local.NodeName != d.hostID {
+		return "", fmt.Errorf("the LVMVolumeGroup %s of the volume is on node %s", lvg.Name, lvg.Spec.Local.NodeName)
+	}
+
+	volumeContext := map[string]string{
+		internal.LVNameKey: llv.Spec.ActualLVNameOnTheNode,
+		internal.VGUUIDKey: lvg.Status.VGUuid,
+	}
+	return d.devicePath(log, volumeContext, lvg.Spec.ActualVGNameOnTheNode, volumeID), nil
+}
+
+// checkLVGrown returns Unavailable, which the kubelet retries, until the LV of
+// a volume has grown to the required bytes. The agent of
+// sds-node-configurator extends it on the node, and a filesystem resized
+// before that does not grow.
+//
+// The LV is checked rather than the device the volume is used through: the
+// LUKS device of an encrypted volume is smaller by its header. A volume the
+// node has no record of has its LV looked up by nodeLVPath.
+func (d *Driver) checkLVGrown(ctx context.Context, log logger.Logger, volumeID, volumePath string, required int64) error {
+	if required == 0 {
+		return nil
+	}
+
+	var lvPath string
+	if volume, ok := d.staged.Get(volumeID); ok {
+		lvPath = volume.LVPath
+	} else if volume, ok := d.published.Get(volumePath); ok {
+		lvPath = volume.LVPath
+	}
+	if lvPath == "" {
+		var err error
+		lvPath, err = d.nodeLVPath(ctx, log, volumeID)
+		if err != nil {
+			log.Error("unable to find the LV of the volume", logger.Err(err))
+			return status.Errorf(codes.Internal, "unable to find the LV of volume %q to check whether it has grown: %v", volumeID, err)
+		}
+	}
+
+	size, err := d.getBlockSizeBytes(lvPath)
+	if err != nil {
+		return status.Errorf(codes.Internal, "unable to get the size of the LV %q: %v", lvPath, err)
+	}
+	if int64(size) < required {
+		log.Info("the LV has not grown to the requested size yet", "lvPath", lvPath, "size", size, "requiredBytes", required)
+		return status.Errorf(codes.Unavailable, "the LV %q of the volume is %d bytes, not yet the %d bytes requested", lvPath, size, required)
+	}
+
+	return nil
+}
+
+// expandBlockVolume returns the size of the device of a block volume, which
+// has no filesystem to resize.
+func (d *Driver) expandBlockVolume(log logger.Logger, request *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
+	devicePath := request.GetVolumePath()
+	if isBlock, err := d.IsBlockDevice(devicePath); err != nil || !isBlock {
+		volume, ok := d.staged.Get(request.GetVolumeId())
+		if !ok {
+			return nil, status.Errorf(codes.FailedPrecondition, "volume %q is not staged on node %s", request.GetVolumeId(), d.hostID)
+		}
+		devicePath = volume.Device
+	}
+
+	size, err := d.getBlockSizeBytes(devicePath)
 	if err != nil {
-		log.Error("unable to resize the filesystem", logger.Err(err))
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, status.Errorf(codes.Internal, "unable to get the size of device %q: %v", devicePath, err)
 	}
 
-	return &csi.NodeExpandVolumeResponse{}, nil
+	log.Info("the block volume has grown", "devicePath", devicePath, "size", size)
+	return &csi.NodeExpandVolumeResponse{CapacityBytes: int64(size)}, nil
 }
 
 func (d *Driver) NodeGetCapabilities(_ context.Context, request *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
@@ -471,6 +1015,90 @@ func (d *Driver) NodeGetInfo(_ context.Context, _ *csi.NodeGetInfoRequest) (*csi
 	}, nil
 }
 
+// stagingDevice returns the device of a volume being staged, reporting a
+// missing one on the claim and the volume.
+func (d *Driver) stagingDevice(ctx context.Context, log logger.Logger, volumeContext map[string]string, vgName, volumeID string) (string, error) {
+	devPath := d.devicePath(log, volumeContext, vgName, volumeID)
+	log.Debug("checking whether the device exists", "devicePath", devPath)
+	exists, err := d.storeManager.PathExists(devPath)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "[NodeStageVolume] Error checking if device exists: %v", err)
+	}
+	if !exists {
+		d.events.Warning(ctx, events.TargetFromParameters(volumeContext), events.ReasonDeviceMissing, "Device %s of the volume is missing on node %s", devPath, d.hostID)
+		return "", status.Errorf(codes.NotFound, "[NodeStageVolume] Device %s not found", devPath)
+	}
+
+	return devPath, nil
+}
+
+// openEncrypted opens the LUKS device of an encrypted volume with the
+// passphrase of the node-stage Secret, formatting it on first use, and returns
+// the path of the plain device.
+func (d *Driver) openEncrypted(ctx context.Context, log logger.Logger, devPath, volumeID string, secrets map[string]string) (string, error) {
+	passphrase := secrets[encryption.SecretKey]
+	if passphrase == "" {
+		return "", status.Errorf(codes.InvalidArgument, "[NodeStageVolume] The volume is encrypted, but its node-stage Secret has no %q", encryption.SecretKey)
+	}
+
+	_, span := tracing.Start(ctx, "OpenLUKS", attribute.String("device", devPath))
+	mapperPath, err := d.storeManager.OpenLUKS(devPath, volumeID, []byte(passphrase))
+	tracing.End(span, err)
+	if err != nil {
+		log.Error("unable to open the LUKS device", logger.Err(err), slog.String("devicePath", devPath))
+		return "", status.Errorf(codes.Internal, "[NodeStageVolume] Error opening the LUKS device %q: %v", devPath, err)
+	}
+
+	return mapperPath, nil
+}
+
+// lvNameFromContext returns the name of the LV of a volume on the node.
+// Volumes created before the name could be set by a template have none in their
+// context: their LV is named after the volume.
+func lvNameFromContext(volumeContext map[string]string, volumeID string) string {
+	if lvName := volumeContext[internal.LVNameKey]; lvName != "" {
+		return lvName
+	}
+
+	return volumeID
+}
+
+// devicePath returns the device of a volume on the node. It is looked up by the
+// UUID of the LV, which survives a rename of the VG and of the LV: the one of
+// the volume context, set for a PersistentVolume created by hand, or the one
+// found when the volume was staged. The LVMLogicalVolume does not report the
+// UUID of its LV, so a volume being staged is looked up by the UUID of the VG
+// recorded at creation and the LV name within it.
+//
+// Volumes created before the VG UUID was recorded, and nodes where the lookup
+// fails, fall back to the path under the VG name of the volume context.
+func (d *Driver) devicePath(log logger.Logger, volumeContext map[string]string, vgName, volumeID string) string {
+	lvName := lvNameFromContext(volumeContext, volumeID)
+	byName := fmt.Sprintf("/dev/%s/%s", vgName, lvName)
+
+	lvUUID := volumeContext[internal.LVUUIDKey]
+	if volume, ok := d.staged.Get(volumeID); ok && lvUUID == "" {
+		lvUUID = volume.LVUUID
+	}
+	vgUUID := volumeContext[internal.VGUUIDKey]
+	if lvUUID == "" && vgUUID == "" {
+		log.Warn("the volume context has no VG UUID, resolving the device by the VG name; the volume stops working if the VG is renamed, recreate it to migrate", "vgName", vgName, "lvName", lvName)
+		return byName
+	}
+
+	devPath, err := d.storeManager.LVDevicePath(lvUUID, vgUUID, lvName)
+	if err != nil {
+		log.Warn("unable to find the LV by its UUIDs, resolving the device by the VG name", "lvUUID", lvUUID, "vgUUID", vgUUID, "vgName", vgName, "lvName", lvName, "error", err.Error())
+		return byName
+	}
+
+	if devPath != byName {
+		log.Info("the VG of the volume has been renamed since its creation", "vgName", vgName, "devicePath", devPath)
+	}
+
+	return devPath
+}
+
 // collectMountOptions returns array of mount options from
 // VolumeCapability_MountVolume and special mount options for
 // given filesystem.
@@ -491,41 +1119,3 @@ func collectMountOptions(fsType string, mountFlags, mountOptions []string) []str
 
 	return mountOptions
 }
-
-func readCString(arr []int8) string {
-	b := make([]byte, 0, len(arr))
-	for _, v := range arr {
-		if v == 0x00 {
-			break
-		}
-		b = append(b, byte(v))
-	}
-	return string(b)
-}
-
-func needLegacyXFSSupport() (bool, error) {
-	// checking if Linux kernel version is <= 5.4
-	var uname syscall.Utsname
-	if err := syscall.Uname(&uname); err != nil {
-		return false, fmt.Errorf("unable to Uname kernel version: %w", err)
-	}
-
-	fullVersion := readCString(uname.Release[:]) // similar to: "6.8.0-44-generic"
-
-	parts := strings.SplitN(fullVersion, ".", 3)
-	if len(parts) < 3 {
-		return false, fmt.Errorf("unexpected kernel version: %s", fullVersion)
-	}
-
-	major, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return false, fmt.Errorf("unexpected kernel version (major part): %s", fullVersion)
-	}
-
-	minor, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return false, fmt.Errorf("unexpected kernel version (minor part): %s", fullVersion)
-	}
-
-	return major < 5 || major == 5 && minor <= 15, nil
-}
@@ -0,0 +1,181 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/encryption"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/tracing"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+	"github.com/deckhouse/sds-node-configurator/api/v1alpha1"
+)
+
+// readOnlyAccess tells whether every capability of a volume is a read-only
+// one, as those of a ReadOnlyMany claim are.
+func readOnlyAccess(capabilities []*csi.VolumeCapability) bool {
+	if len(capabilities) == 0 {
+		return false
+	}
+
+	for _, capability := range capabilities {
+		switch capability.GetAccessMode().GetMode() {
+		case csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY, csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// snapshotMountOptions returns the options a snapshot with a filesystem of
+// fsType is mounted read-only with. The journal is not replayed: the device
+// is read-only, and a write would take blocks of the thin pool anyway. btrfs
+// is not supported, as the snapshot shares the fsid of its origin and only
+// btrfstune, which writes, tells the two apart.
+func snapshotMountOptions(fsType string) ([]string, error) {
+	switch fsType {
+	case internal.FSTypeExt4, internal.FSTypeExt3:
+		return []string{"ro", "noload"}, nil
+	case internal.FSTypeXfs:
+		return []string{"ro", "norecovery"}, nil
+	default:
+		return nil, fmt.Errorf("a snapshot with a %s filesystem cannot be mounted read-only", fsType)
+	}
+}
+
+// snapshotVolume returns the volume of a read-only claim of a snapshot. The
+// volume is the thin snapshot itself, mounted read-only by the node: unlike a
+// restored one, it takes no space of the thin pool and has no
+// LVMLogicalVolume.
+func (d *Driver) snapshotVolume(ctx context.Context, log logger.Logger, request *csi.CreateVolumeRequest, params map[string]string, snapshot *v1alpha1.LVMLogicalVolumeSnapshot, lvg *v1alpha1.LVMVolumeGroup, size resource.Quantity) (*csi.CreateVolumeResponse, error) {
+	// The snapshot would need a passphrase of its own on the node, and the
+	// LUKS device would be opened for writing.
+	if params[encryption.ModeKey] != "" {
+		return nil, status.Error(codes.InvalidArgument, "a snapshot of an encrypted volume cannot be mounted read-only")
+	}
+
+	for _, capability := range request.VolumeCapabilities {
+		mount := capability.GetMount()
+		if mount == nil {
+			continue
+		}
+		fsType := strings.ToLower(mount.GetFsType())
+		if fsType == "" {
+			fsType = defaultFsType
+		}
+		if _, err := snapshotMountOptions(fsType); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	if size.Value() > snapshot.Status.Size.Value() {
+		return nil, status.Errorf(codes.OutOfRange, "requested size %s is greater than the size %s of snapshot %s, which is mounted read-only", size.String(), snapshot.Status.Size.String(), snapshot.Name)
+	}
+
+	// The pool only labels the usage metrics of the volume, and the origin of
+	// the snapshot may be gone.
+	thinPoolName := ""
+	if origin, err := utils.GetLVMLogicalVolume(ctx, d.cl, snapshot.Spec.LVMLogicalVolumeName, ""); err == nil && origin.Spec.Thin != nil {
+		thinPoolName = origin.Spec.Thin.PoolName
+	}
+
+	volumeCtx := maps.Clone(params)
+	volumeCtx[internal.SubPath] = request.Name
+	volumeCtx[internal.VGNameKey] = snapshot.Status.ActualVGNameOnTheNode
+	if lvg.Status.VGUuid != "" {
+		volumeCtx[internal.VGUUIDKey] = lvg.Status.VGUuid
+	}
+	volumeCtx[internal.LVNameKey] = snapshot.Spec.ActualSnapshotNameOnTheNode
+	volumeCtx[internal.ThinPoolNameKey] = thinPoolName
+	volumeCtx[internal.SnapshotKey] = snapshot.Name
+
+	log.Info("read-only snapshot volume created successfully", "llvsName", snapshot.Name, "volumeContext", fmt.Sprintf("%+v", volumeCtx))
+
+	return &csi.CreateVolumeResponse{
+		Volume: &csi.Volume{
+			CapacityBytes: snapshot.Status.Size.Value(),
+			VolumeId:      request.Name,
+			VolumeContext: volumeCtx,
+			ContentSource: request.VolumeContentSource,
+			AccessibleTopology: []*csi.Topology{
+				{Segments: map[string]string{
+					internal.TopologyKey: snapshot.Status.NodeName,
+				}},
+			},
+		},
+	}, nil
+}
+
+// isSnapshotVolume tells whether a volume is a read-only snapshot volume,
+// which only the volume attributes of its PersistentVolume tell.
+func (d *Driver) isSnapshotVolume(ctx context.Context, volumeID string) (bool, error) {
+	pv := &corev1.PersistentVolume{}
+	if err := d.cl.Get(ctx, client.ObjectKey{Name: volumeID}, pv); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	return pv.Spec.CSI != nil && pv.Spec.CSI.VolumeAttributes[internal.SnapshotKey] != "", nil
+}
+
+// snapshotUser returns the name of a PersistentVolume that exposes the
+// snapshot, or "" if there is none.
+func (d *Driver) snapshotUser(ctx context.Context, snapshotID string) (string, error) {
+	pvs := &corev1.PersistentVolumeList{}
+	if err := d.cl.List(ctx, pvs); err != nil {
+		return "", err
+	}
+
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == DefaultDriverName && pv.Spec.CSI.VolumeAttributes[internal.SnapshotKey] == snapshotID {
+			return pv.Name, nil
+		}
+	}
+
+	return "", nil
+}
+
+// activateSnapshot activates the thin snapshot of a read-only snapshot volume
+// and makes its device read-only before NodeStageVolume looks the device up.
+func (d *Driver) activateSnapshot(ctx context.Context, log logger.Logger, volumeContext map[string]string, vgName, volumeID string) error {
+	lvPath := d.devicePath(log, volumeContext, vgName, volumeID)
+
+	_, span := tracing.Start(ctx, "ActivateSnapshot", attribute.String("device", lvPath))
+	err := d.storeManager.ActivateReadOnly(lvPath)
+	tracing.End(span, err)
+	if err != nil {
+		log.Error("unable to activate the snapshot", logger.Err(err), slog.String("lvPath", lvPath))
+		return status.Errorf(codes.Internal, "[NodeStageVolume] Unable to activate snapshot %q of volume %q: %v", lvPath, volumeID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+)
+
+func TestReadOnlyAccess(t *testing.T) {
+	capability := func(mode csi.VolumeCapability_AccessMode_Mode) *csi.VolumeCapability {
+		return &csi.VolumeCapability{AccessMode: &csi.VolumeCapability_AccessMode{Mode: mode}}
+	}
+
+	for _, tc := range []struct {
+		name         string
+		capabilities []*csi.VolumeCapability
+		want         bool
+	}{
+		{name: "none"},
+		{name: "read_only_many", capabilities: []*csi.VolumeCapability{capability(csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY)}, want: true},
+		{name: "read_only", capabilities: []*csi.VolumeCapability{capability(csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY)}, want: true},
+		{name: "read_write_once", capabilities: []*csi.VolumeCapability{capability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER)}},
+		{name: "mixed", capabilities: []*csi.VolumeCapability{
+			capability(csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY),
+			capability(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER),
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, readOnlyAccess(tc.capabilities))
+		})
+	}
+}
+
+func TestSnapshotMountOptions(t *testing.T) {
+	options, err := snapshotMountOptions(internal.FSTypeExt4)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ro", "noload"}, options)
+
+	options, err = snapshotMountOptions(internal.FSTypeXfs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ro", "norecovery"}, options)
+	assert.Equal(t, []string{"ro", "norecovery", "nouuid"}, collectMountOptions(internal.FSTypeXfs, options, nil))
+
+	_, err = snapshotMountOptions(internal.FSTypeBtrfs)
+	assert.Error(t, err)
+}
+
+func TestIsSnapshotVolume(t *testing.T) {
+	pv := func(name string, attributes map[string]string) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: DefaultDriverName, VolumeHandle: name, VolumeAttributes: attributes},
+				},
+			},
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	d := &Driver{cl: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		pv("pvc-snapshot", map[string]string{internal.SnapshotKey: "snapshot-1"}),
+		pv("pvc-volume", map[string]string{internal.LVNameKey: "pvc-volume"}),
+	).Build()}
+
+	for volumeID, want := range map[string]bool{"pvc-snapshot": true, "pvc-volume": false, "pvc-missing": false} {
+		t.Run(volumeID, func(t *testing.T) {
+			snapshot, err := d.isSnapshotVolume(context.Background(), volumeID)
+			require.NoError(t, err)
+			assert.Equal(t, want, snapshot)
+		})
+	}
+}
+
+func TestSnapshotUser(t *testing.T) {
+	pv := func(name string, attributes map[string]string) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: DefaultDriverName, VolumeHandle: name, VolumeAttributes: attributes},
+				},
+			},
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	d := &Driver{cl: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		pv("pvc-snapshot", map[string]string{internal.SnapshotKey: "snapshot-1"}),
+		pv("pvc-volume", map[string]string{internal.LVNameKey: "pvc-volume"}),
+	).Build()}
+
+	for snapshotID, want := range map[string]string{"snapshot-1": "pvc-snapshot", "snapshot-2": ""} {
+		t.Run(snapshotID, func(t *testing.T) {
+			pvName, err := d.snapshotUser(context.Background(), snapshotID)
+			require.NoError(t, err)
+			assert.Equal(t, want, pvName)
+		})
+	}
+}
+
+// unstageStore records the LVs it activates and deactivates.
+type unstageStore struct {
+	utils.NodeStoreManager
+	activated   []string
+	deactivated []string
+}
+
+func (s *unstageStore) ActivateReadOnly(lvPath string) error {
+	s.activated = append(s.activated, lvPath)
+	return nil
+}
+
+func (s *unstageStore) Unstage(string) error {
+	return nil
+}
+
+func (s *unstageStore) CloseLUKS(string) error {
+	return nil
+}
+
+func (s *unstageStore) DeactivateReadOnly(lvPath string) error {
+	s.deactivated = append(s.deactivated, lvPath)
+	return nil
+}
+
+// The thin snapshot of a snapshot volume is deactivated once it is unstaged;
+// the LV of a writable volume is left alone.
+func TestNodeUnstageSnapshotVolume(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		readOnly    bool
+		deactivated []string
+	}{
+		{name: "snapshot", readOnly: true, deactivated: []string{"/dev/vg-1/snapshot-1"}},
+		{name: "volume"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &unstageStore{}
+			d := &Driver{
+				log:          logger.NewNop(),
+				storeManager: store,
+				inFlight:     internal.NewInFlight(),
+				staged:       internal.NewStagedVolumes(filepath.Join(t.TempDir(), stagedVolumesFile)),
+			}
+			require.NoError(t, d.staged.Set("pvc-1", internal.StagedVolume{StagingPath: "/staging/pvc-1", LVPath: "/dev/vg-1/snapshot-1", ReadOnly: tc.readOnly}))
+
+			_, err := d.NodeUnstageVolume(context.Background(), &csi.NodeUnstageVolumeRequest{VolumeId: "pvc-1", StagingTargetPath: "/staging/pvc-1"})
+			require.NoError(t, err)
+			assert.Equal(t, tc.deactivated, store.deactivated)
+			_, staged := d.staged.Get("pvc-1")
+			assert.False(t, staged)
+		})
+	}
+}
+
+// A block snapshot volume is not activated while another operation, such as
+// NodeUnstageVolume deactivating it, holds the volume.
+func TestNodeStageBlockSnapshotInFlight(t *testing.T) {
+	store := &unstageStore{}
+	d := &Driver{
+		log:          logger.NewNop(),
+		storeManager: store,
+		inFlight:     internal.NewInFlight(),
+		staged:       internal.NewStagedVolumes(filepath.Join(t.TempDir(), stagedVolumesFile)),
+	}
+	require.True(t, d.inFlight.InsertOperation("pvc-1", "NodeUnstageVolume", ""))
+
+	_, err := d.NodeStageVolume(context.Background(), &csi.NodeStageVolumeRequest{
+		VolumeId:          "pvc-1",
+		StagingTargetPath: "/staging/pvc-1",
+		VolumeCapability:  &csi.VolumeCapability{AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}}},
+		VolumeContext:     map[string]string{internal.VGNameKey: "vg-1", internal.SnapshotKey: "snapshot-1"},
+	})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.Empty(t, store.activated)
+}
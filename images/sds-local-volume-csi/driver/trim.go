@@ -0,0 +1,172 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+)
+
+// scheduleTrim sets the periodic discard of a volume being staged from the
+// parameters of its class. A volume staged again keeps the time of its last
+// trim; a new one, which mkfs has discarded already, waits a whole interval.
+func (d *Driver) scheduleTrim(log logger.Logger, volumeContext map[string]string, volumeID string, volume *internal.StagedVolume) {
+	interval, err := time.ParseDuration(volumeContext[internal.DiscardIntervalKey])
+	if err != nil || interval <= 0 {
+		log.Warn("invalid discard interval, the volume is not trimmed", slog.String("interval", volumeContext[internal.DiscardIntervalKey]))
+		return
+	}
+	concurrency, err := strconv.Atoi(volumeContext[internal.DiscardConcurrencyKey])
+	if err != nil || concurrency <= 0 {
+		concurrency = 1
+	}
+
+	volume.TrimInterval = interval
+	volume.TrimConcurrency = concurrency
+	volume.LastTrim = time.Now()
+	if staged, ok := d.staged.Get(volumeID); ok && !staged.LastTrim.IsZero() {
+		volume.LastTrim = staged.LastTrim
+	}
+}
+
+// trimVolumes runs the periodic discard of the staged volumes every
+// trimCheckInterval until ctx is cancelled.
+func (d *Driver) trimVolumes(ctx context.Context) {
+	log := d.log.Named("trimVolumes")
+
+	ticker := time.NewTicker(trimCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		d.trimDueVolumes(ctx, log, time.Now())
+	}
+}
+
+// trimDueVolumes trims the staged volumes whose interval has passed since
+// their last trim. The classes are trimmed side by side, the volumes of a
+// class at most TrimConcurrency at a time. Once ctx is done, the running
+// fstrims are killed and no other volume is trimmed.
+func (d *Driver) trimDueVolumes(ctx context.Context, log logger.Logger, now time.Time) {
+	volumes := d.staged.List()
+
+	due := map[string][]string{}
+	for volumeID, volume := range volumes {
+		if volume.TrimInterval > 0 && now.Sub(volume.LastTrim) >= volume.TrimInterval {
+			due[volume.LocalStorageClass] = append(due[volume.LocalStorageClass], volumeID)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, volumeIDs := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var eg errgroup.Group
+			eg.SetLimit(volumes[volumeIDs[0]].TrimConcurrency)
+			for _, volumeID := range volumeIDs {
+				eg.Go(func() error {
+					d.trimVolume(ctx, log.With("volumeID", volumeID), volumeID, now)
+					return nil
+				})
+			}
+			_ = eg.Wait()
+		}()
+	}
+	wg.Wait()
+}
+
+// trimVolume runs fstrim on a staged volume. The volume is held in flight
+// meanwhile, so that it is not unstaged under fstrim; a volume busy with an
+// operation is trimmed on the next check. A failed trim is retried only after
+// the next interval, not on every check; an interrupted one is not recorded,
+// so it runs again after a restart.
+func (d *Driver) trimVolume(ctx context.Context, log logger.Logger, volumeID string, now time.Time) {
+	if ctx.Err() != nil {
+		return
+	}
+	if !d.inFlight.InsertOperation(volumeID, "Trim", "") {
+		log.Debug("the volume is busy, trimming it later")
+		return
+	}
+	defer d.inFlight.Delete(volumeID)
+
+	volume, ok := d.staged.Get(volumeID)
+	if !ok {
+		return
+	}
+
+	reclaimed, err := d.trim(ctx, log, volume)
+	switch {
+	case ctx.Err() != nil:
+		log.Info("the trim of the volume is interrupted")
+		return
+	case err != nil:
+		log.Warn("unable to trim the volume", logger.Err(err))
+		d.metrics.AddTrimFailure(volume.VGName, volume.ThinPoolName)
+	default:
+		d.metrics.AddTrimmed(volume.VGName, volume.ThinPoolName, reclaimed)
+	}
+
+	volume.LastTrim = now
+	if err := d.staged.Set(volumeID, volume); err != nil {
+		log.Error("unable to record the trim of the volume", logger.Err(err))
+	}
+}
+
+// trim runs fstrim on a staged thin volume and returns the bytes it has
+// returned to the thin pool. fstrim reports the size of the ranges it has
+// discarded, which on xfs is the whole free space of the filesystem on every
+// run, so the space returned is told by the bytes the thin LV has mapped
+// before and after. Blocks written meanwhile are taken off it; when the mapped
+// bytes cannot be read, nothing is counted.
+func (d *Driver) trim(ctx context.Context, log logger.Logger, volume internal.StagedVolume) (uint64, error) {
+	before, _, _, beforeErr := d.storeManager.ThinVolumeUsage(volume.LVPath)
+
+	discarded, err := d.storeManager.Trim(ctx, volume.StagingPath)
+	if err != nil {
+		return 0, err
+	}
+
+	after, _, _, afterErr := d.storeManager.ThinVolumeUsage(volume.LVPath)
+	if err := errors.Join(beforeErr, afterErr); err != nil {
+		log.Warn("unable to get the mapped bytes of the volume, the space returned to the thin pool is not counted", logger.Err(err))
+		return 0, nil
+	}
+
+	var reclaimed uint64
+	if before > after {
+		reclaimed = before - after
+	}
+	log.Debug("trimmed the volume", slog.Uint64("discardedBytes", discarded), slog.Uint64("reclaimedBytes", reclaimed))
+	return reclaimed, nil
+}
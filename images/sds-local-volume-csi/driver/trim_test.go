@@ -0,0 +1,137 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+)
+
+// trimStore records the paths it trims. ThinVolumeUsage returns the mapped
+// bytes in turn and then keeps the last ones, and fails if there are none.
+type trimStore struct {
+	utils.NodeStoreManager
+	mu      sync.Mutex
+	trimmed []string
+	mapped  []uint64
+}
+
+func (s *trimStore) ThinVolumeUsage(string) (uint64, uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.mapped) == 0 {
+		return 0, 0, true, errors.New("no data_percent")
+	}
+	used := s.mapped[0]
+	if len(s.mapped) > 1 {
+		s.mapped = s.mapped[1:]
+	}
+	return used, 0, true, nil
+}
+
+func (s *trimStore) Trim(_ context.Context, path string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trimmed = append(s.trimmed, path)
+	return 1024, nil
+}
+
+func TestTrimDueVolumes(t *testing.T) {
+	now := time.Now()
+	store := &trimStore{}
+	d := &Driver{
+		storeManager: store,
+		inFlight:     internal.NewInFlight(),
+		staged:       internal.NewStagedVolumes(filepath.Join(t.TempDir(), stagedVolumesFile)),
+	}
+
+	for volumeID, volume := range map[string]internal.StagedVolume{
+		"due":       {StagingPath: "/staging/due", TrimInterval: time.Hour, TrimConcurrency: 1, LastTrim: now.Add(-2 * time.Hour)},
+		"not-due":   {StagingPath: "/staging/not-due", TrimInterval: time.Hour, TrimConcurrency: 1, LastTrim: now.Add(-time.Minute)},
+		"untrimmed": {StagingPath: "/staging/untrimmed"},
+		"busy":      {StagingPath: "/staging/busy", TrimInterval: time.Hour, TrimConcurrency: 1},
+	} {
+		require.NoError(t, d.staged.Set(volumeID, volume))
+	}
+	// A volume being unstaged is left to the next check.
+	require.True(t, d.inFlight.InsertOperation("busy", "NodeUnstageVolume", ""))
+
+	d.trimDueVolumes(context.Background(), logger.NewNop(), now)
+
+	assert.Equal(t, []string{"/staging/due"}, store.trimmed)
+	due, _ := d.staged.Get("due")
+	assert.True(t, due.LastTrim.Equal(now))
+	busy, _ := d.staged.Get("busy")
+	assert.True(t, busy.LastTrim.IsZero())
+}
+
+// No volume is trimmed once the plugin is shutting down, and the interrupted
+// trims are not recorded.
+func TestTrimDueVolumesCancelled(t *testing.T) {
+	now := time.Now()
+	store := &trimStore{}
+	d := &Driver{
+		storeManager: store,
+		inFlight:     internal.NewInFlight(),
+		staged:       internal.NewStagedVolumes(filepath.Join(t.TempDir(), stagedVolumesFile)),
+	}
+	require.NoError(t, d.staged.Set("due", internal.StagedVolume{StagingPath: "/staging/due", TrimInterval: time.Hour, TrimConcurrency: 1}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d.trimDueVolumes(ctx, logger.NewNop(), now)
+
+	assert.Empty(t, store.trimmed)
+	due, _ := d.staged.Get("due")
+	assert.True(t, due.LastTrim.IsZero())
+}
+
+// The space returned to the thin pool is what the volume no longer maps, not
+// what fstrim reports as discarded.
+func TestTrimReclaimed(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		mapped    []uint64
+		reclaimed uint64
+	}{
+		{name: "reclaimed", mapped: []uint64{3 << 20, 1 << 20}, reclaimed: 2 << 20},
+		{name: "nothing_to_reclaim", mapped: []uint64{1 << 20}, reclaimed: 0},
+		{name: "written_meanwhile", mapped: []uint64{1 << 20, 2 << 20}, reclaimed: 0},
+		{name: "usage_unknown", reclaimed: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &trimStore{mapped: tc.mapped}
+			d := &Driver{storeManager: store}
+
+			reclaimed, err := d.trim(context.Background(), logger.NewNop(), internal.StagedVolume{StagingPath: "/staging/pvc-1", LVPath: "/dev/vg-1/pvc-1"})
+			require.NoError(t, err)
+			assert.Equal(t, tc.reclaimed, reclaimed)
+			assert.Equal(t, []string{"/staging/pvc-1"}, store.trimmed)
+		})
+	}
+}
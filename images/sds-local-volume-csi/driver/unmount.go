@@ -0,0 +1,113 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/events"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+)
+
+// unmountFailures counts the failed unmounts of every target since its last
+// successful one. The zero value is ready to use.
+type unmountFailures struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// add records a failed unmount of target and returns the failures so far.
+func (f *unmountFailures) add(target string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.counts == nil {
+		f.counts = map[string]int{}
+	}
+	f.counts[target]++
+	return f.counts[target]
+}
+
+func (f *unmountFailures) reset(target string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.counts, target)
+}
+
+// unmount unmounts the staging or the publish target of a volume for method.
+// A busy mount gets the processes holding it in the error and in an event of
+// the PVC, and is unmounted lazily once it has failed lazyUnmountAfter times
+// in a row, if that is set.
+func (d *Driver) unmount(ctx context.Context, log logger.Logger, method, volumeID, target string) error {
+	err := d.storeManager.Unstage(target)
+	if err == nil {
+		d.unmountFailures.reset(target)
+		return nil
+	}
+
+	failures := d.unmountFailures.add(target)
+	if !busy(err) {
+		return status.Errorf(codes.Internal, "[%s] Error unmounting volume %q mounted at %q: %v", method, volumeID, target, err)
+	}
+
+	holders, holdersErr := d.storeManager.MountHolders(hostProcRoot, target)
+	if holdersErr != nil {
+		log.Warn("unable to find the processes holding the mount", logger.Err(holdersErr))
+	}
+	description := utils.DescribeHolders(holders)
+	log.Warn("the mount is busy", "target", target, "failures", failures, slog.String("holders", description))
+
+	pvc := d.volumeTarget(volumeID)
+	if d.lazyUnmountAfter > 0 && failures >= d.lazyUnmountAfter {
+		if err := d.storeManager.LazyUnmount(target); err != nil {
+			log.Error("unable to unmount lazily", logger.Err(err))
+		} else {
+			d.unmountFailures.reset(target)
+			log.Warn("unmounted lazily", "target", target)
+			d.events.Warning(ctx, pvc, events.ReasonLazyUnmounted, "Mount %s of the volume on node %s was busy %d times and has been detached lazily, the filesystem is unmounted once it is let go of. Held by: %s", target, d.hostID, failures, description)
+			return nil
+		}
+	}
+
+	d.events.Warning(ctx, pvc, events.ReasonUnmountBusy, "Unable to unmount %s of the volume on node %s, the mount is busy. Held by: %s", target, d.hostID, description)
+	return status.Errorf(codes.Internal, "[%s] Error unmounting volume %q mounted at %q: %v; held by: %s", method, volumeID, target, err, description)
+}
+
+// volumeTarget returns the event target of a staged volume, which
+// NodeUnstageVolume and NodeUnpublishVolume have no volume context to read
+// from.
+func (d *Driver) volumeTarget(volumeID string) events.Target {
+	target := events.Target{PVName: volumeID}
+	if volume, ok := d.staged.Get(volumeID); ok {
+		target.PVCName = volume.PVCName
+		target.PVCNamespace = volume.PVCNamespace
+	}
+	return target
+}
+
+// busy tells whether an unmount failed because the mount is in use. umount
+// reports it as "target is busy" and the unmount syscall as EBUSY, "device or
+// resource busy".
+func busy(err error) bool {
+	return strings.Contains(err.Error(), "busy")
+}
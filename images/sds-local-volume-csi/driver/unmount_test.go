@@ -0,0 +1,119 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/events"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/utils"
+)
+
+// busyStore fails every unmount with err and unmounts lazily.
+type busyStore struct {
+	utils.NodeStoreManager
+	err    error
+	lazy   []string
+	lookup int
+}
+
+func (s *busyStore) Unstage(string) error {
+	return s.err
+}
+
+func (s *busyStore) MountHolders(string, string) ([]utils.MountHolder, error) {
+	s.lookup++
+	return []utils.MountHolder{{PID: 42, Command: "postgres", By: utils.HeldByOpenFile}}, nil
+}
+
+func (s *busyStore) LazyUnmount(target string) error {
+	s.lazy = append(s.lazy, target)
+	return nil
+}
+
+func TestUnmount(t *testing.T) {
+	const target = "/var/lib/kubelet/pods/uid-1/volumes/kubernetes.io~csi/pvc-1/mount"
+	newDriver := func(t *testing.T, store *busyStore, lazyUnmountAfter int) (*Driver, *record.FakeRecorder) {
+		scheme := runtime.NewScheme()
+		require.NoError(t, clientgoscheme.AddToScheme(scheme))
+		pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "db"}}
+		cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pvc).Build()
+
+		recorder := record.NewFakeRecorder(10)
+		d := &Driver{
+			hostID:           "node-1",
+			storeManager:     store,
+			staged:           internal.NewStagedVolumes(filepath.Join(t.TempDir(), stagedVolumesFile)),
+			events:           events.NewRecorderFor(cl, recorder, logger.NewNop()),
+			lazyUnmountAfter: lazyUnmountAfter,
+		}
+		require.NoError(t, d.staged.Set("pvc-1", internal.StagedVolume{PVCName: "data", PVCNamespace: "db"}))
+		return d, recorder
+	}
+
+	t.Run("busy", func(t *testing.T) {
+		store := &busyStore{err: errors.New("umount: " + target + ": target is busy.")}
+		d, recorder := newDriver(t, store, 0)
+
+		for range 3 {
+			err := d.unmount(context.Background(), logger.NewNop(), "NodeUnpublishVolume", "pvc-1", target)
+			assert.Equal(t, codes.Internal, status.Code(err))
+			assert.Contains(t, err.Error(), "held by: pid 42 (postgres) by open file")
+		}
+		assert.Empty(t, store.lazy)
+		assert.Contains(t, <-recorder.Events, "Warning UnmountBusy")
+	})
+
+	t.Run("lazy_after_failures", func(t *testing.T) {
+		store := &busyStore{err: errors.New("umount: " + target + ": target is busy.")}
+		d, recorder := newDriver(t, store, 2)
+
+		err := d.unmount(context.Background(), logger.NewNop(), "NodeUnpublishVolume", "pvc-1", target)
+		assert.Error(t, err)
+		assert.Contains(t, <-recorder.Events, "Warning UnmountBusy")
+		err = d.unmount(context.Background(), logger.NewNop(), "NodeUnpublishVolume", "pvc-1", target)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{target}, store.lazy)
+		assert.Contains(t, <-recorder.Events, "Warning LazyUnmounted")
+	})
+
+	t.Run("not_busy", func(t *testing.T) {
+		store := &busyStore{err: errors.New("umount: " + target + ": permission denied")}
+		d, _ := newDriver(t, store, 1)
+
+		err := d.unmount(context.Background(), logger.NewNop(), "NodeUnpublishVolume", "pvc-1", target)
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.Zero(t, store.lookup)
+		assert.Empty(t, store.lazy)
+	})
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+)
+
+// volumeCondition checks a volume for the failures the kubelet should know
+// about, for NodeGetVolumeStats: its device gone, its filesystem turned
+// read-only after an error, its thin pool full or its staging mount gone. The
+// devices and the thin pool are only known for a volume published since the
+// plugin records them; the other checks need nothing but the request.
+func (d *Driver) volumeCondition(log logger.Logger, req *csi.NodeGetVolumeStatsRequest, isBlock bool) *csi.VolumeCondition {
+	var problems []string
+	check := func(problem string, err error) {
+		switch {
+		case err != nil:
+			// A check that cannot run says nothing about the volume.
+			log.Warn("unable to check the volume", logger.Err(err))
+		case problem != "":
+			problems = append(problems, problem)
+		}
+	}
+
+	volume, published := d.published.Get(req.GetVolumePath())
+	if published {
+		check(d.checkDevices(volume))
+		check(d.checkThinPool(volume))
+	}
+
+	if !isBlock {
+		if published && !volume.Readonly {
+			check(d.checkWritable(req.GetVolumePath(), "volume"))
+		}
+		if staging := req.GetStagingTargetPath(); staging != "" {
+			check(d.checkStaged(staging))
+			if published && !volume.Readonly {
+				check(d.checkWritable(staging, "staging"))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &csi.VolumeCondition{Abnormal: true, Message: strings.Join(problems, "; ")}
+	}
+
+	return &csi.VolumeCondition{Abnormal: false}
+}
+
+func (d *Driver) checkDevices(volume internal.PublishedVolume) (string, error) {
+	for _, device := range slices.Compact([]string{volume.LVPath, volume.Device}) {
+		exists, err := d.storeManager.PathExists(device)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return fmt.Sprintf("the device %s is missing on node %s", device, d.hostID), nil
+		}
+	}
+
+	return "", nil
+}
+
+func (d *Driver) checkThinPool(volume internal.PublishedVolume) (string, error) {
+	full, pool, err := d.storeManager.ThinPoolOutOfDataSpace(volume.LVPath)
+	if err != nil || !full {
+		return "", err
+	}
+
+	return fmt.Sprintf("the thin pool %s is out of data space, writes fail or hang", pool), nil
+}
+
+// checkWritable reports a mount that is read-only although the volume was
+// published read-write: ext4 with errors=remount-ro turns itself read-only on
+// a filesystem error.
+func (d *Driver) checkWritable(path, what string) (string, error) {
+	mount, err := d.storeManager.FindMount(path)
+	if err != nil || mount == nil {
+		return "", err
+	}
+
+	if slices.Contains(mount.Opts, "ro") {
+		return fmt.Sprintf("the %s mount %s is read-only, the filesystem has likely hit an error, see the kernel log of node %s", what, path, d.hostID), nil
+	}
+
+	return "", nil
+}
+
+func (d *Driver) checkStaged(staging string) (string, error) {
+	mount, err := d.storeManager.FindMount(staging)
+	if err != nil {
+		return "", err
+	}
+	if mount == nil {
+		return fmt.Sprintf("the staging mount %s is missing", staging), nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/monitoring"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/qos"
+)
+
+// volumeLabelsTimeout bounds the lookup of the PersistentVolume of a volume
+// being staged. The labels are not worth holding up the pod for.
+const volumeLabelsTimeout = 5 * time.Second
+
+// recordStagedVolume records a staged volume for the usage metrics. A volume
+// that cannot be recorded only goes without them.
+func (d *Driver) recordStagedVolume(ctx context.Context, log logger.Logger, volumeContext map[string]string, volumeID string, volume internal.StagedVolume) {
+	volume.VGName = volumeContext[internal.VGNameKey]
+	volume.ThinPoolName = volumeContext[internal.ThinPoolNameKey]
+	volume.PVCName = volumeContext[internal.PVCNameKey]
+	volume.PVCNamespace = volumeContext[internal.PVCNamespaceKey]
+	volume.Parameters = mutableParameters(volumeContext)
+
+	// The later lookups of the device select the LV by its UUID.
+	lvUUID, err := d.storeManager.LVUUID(volume.LVPath)
+	if err != nil {
+		log.Warn("unable to get the UUID of the LV, its device is looked up by the names", logger.Err(err), slog.String("lvPath", volume.LVPath))
+	}
+	volume.LVUUID = lvUUID
+
+	// The LocalStorageClass is the StorageClass of the same name, which only
+	// the PersistentVolume tells.
+	pvName := volumeContext[internal.PVNameKey]
+	if pvName == "" {
+		pvName = volumeID
+	}
+	ctx, cancel := context.WithTimeout(ctx, volumeLabelsTimeout)
+	defer cancel()
+	pv := &corev1.PersistentVolume{}
+	if err := d.cl.Get(ctx, client.ObjectKey{Name: pvName}, pv); err != nil {
+		log.Warn("unable to get the PersistentVolume, the usage metrics of the volume go without its LocalStorageClass", logger.Err(err), slog.String("pvName", pvName))
+	} else {
+		volume.LocalStorageClass = pv.Spec.StorageClassName
+		if ref := pv.Spec.ClaimRef; ref != nil && volume.PVCName == "" {
+			volume.PVCName, volume.PVCNamespace = ref.Name, ref.Namespace
+		}
+	}
+
+	if volumeContext[internal.DiscardKey] == internal.DiscardPeriodic && volume.ThinPoolName != "" && !volume.Block && !volume.ReadOnly && volume.Device == volume.LVPath {
+		d.scheduleTrim(log, volumeContext, volumeID, &volume)
+	}
+
+	if err := d.staged.Set(volumeID, volume); err != nil {
+		log.Error("unable to record the staged volume, it goes without usage metrics", logger.Err(err))
+	}
+}
+
+// forgetStagedVolume drops an unstaged volume and its usage metrics.
+func (d *Driver) forgetStagedVolume(log logger.Logger, volumeID string) {
+	if err := d.staged.Delete(volumeID); err != nil {
+		log.Error("unable to forget the staged volume", logger.Err(err))
+	}
+	d.metrics.DeleteVolumeUsage(volumeID)
+}
+
+// collectVolumeMetrics publishes the usage of the staged volumes, and reports
+// the ones to autoscale to the controller, every volumeMetricsInterval until
+// ctx is cancelled.
+func (d *Driver) collectVolumeMetrics(ctx context.Context) {
+	log := d.log.Named("collectVolumeMetrics")
+
+	ticker := time.NewTicker(volumeMetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		// The pods of a volume share its IO limits; a volume no pod uses has
+		// none.
+		limits := map[string]qos.Limits{}
+		for _, volume := range d.published.List() {
+			limits[volume.VolumeID] = volume.Limits
+		}
+
+		specs := autoscalingSpecs{}
+		autoscaling := map[string]autoscalingUsage{}
+		for volumeID, volume := range d.staged.List() {
+			labels := monitoring.VolumeLabels{
+				Namespace:         volume.PVCNamespace,
+				PVC:               volume.PVCName,
+				LocalStorageClass: volume.LocalStorageClass,
+				VolumeGroup:       volume.VGName,
+				ThinPool:          volume.ThinPoolName,
+			}
+			l := limits[volumeID]
+			d.metrics.SetVolumeIOLimits(volumeID, labels, monitoring.VolumeIOLimits{ReadIOPS: l.ReadIOPS, WriteIOPS: l.WriteIOPS, ReadBPS: l.ReadBPS, WriteBPS: l.WriteBPS})
+
+			usage, err := d.stagedVolumeUsage(log, volume)
+			if err != nil {
+				log.Debug("unable to get the usage of a staged volume", slog.String("volumeID", volumeID), logger.Err(err))
+				continue
+			}
+
+			d.metrics.SetVolumeUsage(volumeID, labels, usage)
+
+			if reported, ok := d.autoscalingUsageOf(ctx, log, volume, usage, specs); ok {
+				autoscaling[volumeID] = reported
+			}
+		}
+		d.reportAutoscaling(ctx, log, autoscaling)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stagedVolumeUsage returns the usage of a staged volume: that of its
+// filesystem, or that of a block volume as NodeGetVolumeStats reports it.
+func (d *Driver) stagedVolumeUsage(log logger.Logger, volume internal.StagedVolume) (monitoring.VolumeUsage, error) {
+	if !volume.Block {
+		bytes, _, err := fsUsage(volume.StagingPath)
+		if err != nil {
+			return monitoring.VolumeUsage{}, err
+		}
+		return monitoring.VolumeUsage{Capacity: bytes.Total, Used: bytes.Used, Available: bytes.Available, UsageKnown: true}, nil
+	}
+
+	size, err := d.getBlockSizeBytes(volume.Device)
+	if err != nil {
+		return monitoring.VolumeUsage{}, err
+	}
+	usage, known := d.blockVolumeUsage(log, volume.LVPath, size)
+	return monitoring.VolumeUsage{Capacity: usage.Total, Used: usage.Used, Available: usage.Available, UsageKnown: known}, nil
+}
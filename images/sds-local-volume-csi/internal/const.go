@@ -24,19 +24,71 @@ const (
 	LVMVolumeGroupKey           = "local.csi.storage.deckhouse.io/lvm-volume-groups"
 	LVMVThickContiguousParamKey = "local.csi.storage.deckhouse.io/lvm-thick-contiguous"
 	ActualNameOnTheNodeKey      = "local.csi.storage.deckhouse.io/actualNameOnTheNode"
+	LVNameTemplateKey           = "local.csi.storage.deckhouse.io/lvm-lv-name-template"
+	LVTagsKey                   = "local.csi.storage.deckhouse.io/lv-tags"
 	TopologyKey                 = "topology.sds-local-volume-csi/node"
 	SubPath                     = "subPath"
 	VGNameKey                   = "vgname"
+	LVNameKey                   = "lvname"
+	VGUUIDKey                   = "vguuid"
+	LVUUIDKey                   = "lvuuid"
 	ThinPoolNameKey             = "thinPoolName"
 	LVMTypeThin                 = "Thin"
 	LVMTypeThick                = "Thick"
 	LLVStatusCreated            = "Created"
 	LLVSStatusCreated           = "Created"
+	LVGStatusReady              = "Ready"
 	BindingModeWFFC             = "WaitForFirstConsumer"
 	BindingModeI                = "Immediate"
 	FSTypeKey                   = "csi.storage.k8s.io/fstype"
 
+	// set by the external-provisioner started with --extra-create-metadata
+	PVCNameKey      = "csi.storage.k8s.io/pvc/name"
+	PVCNamespaceKey = "csi.storage.k8s.io/pvc/namespace"
+	PVNameKey       = "csi.storage.k8s.io/pv/name"
+
+	// Returning freed blocks of thin volumes to their pool, set by the
+	// controller from spec.lvm.discard of the LocalStorageClass.
+	DiscardKey            = "local.csi.storage.deckhouse.io/discard"
+	DiscardIntervalKey    = "local.csi.storage.deckhouse.io/discard-interval"
+	DiscardConcurrencyKey = "local.csi.storage.deckhouse.io/discard-concurrency"
+	DiscardOnline         = "Online"
+	DiscardPeriodic       = "Periodic"
+
+	// MkfsOptionsKey holds the mkfs options of the LocalStorageClass, a flag
+	// and its value at a time separated by spaces.
+	MkfsOptionsKey = "local.csi.storage.deckhouse.io/mkfs-options"
+
+	// FsckPolicyKey holds the fsckPolicy of the LocalStorageClass, how a
+	// filesystem is checked before NodeStageVolume mounts it.
+	FsckPolicyKey          = "local.csi.storage.deckhouse.io/fsck-policy"
+	FsckPolicyNone         = "None"
+	FsckPolicyPreen        = "Preen"
+	FsckPolicyForceOnDirty = "ForceOnDirty"
+	// FsckOverrideAnnotation set to "true" on a PVC mounts its volume even
+	// though the check of its filesystem found corruption or failed.
+	FsckOverrideAnnotation = "local.csi.storage.deckhouse.io/fsck-override"
+
+	// SnapshotKey holds the LVMLogicalVolumeSnapshot a read-only volume is
+	// mounted from. Such a volume has no LVMLogicalVolume: its LV is the thin
+	// snapshot itself.
+	SnapshotKey = "local.csi.storage.deckhouse.io/snapshot"
+
+	// AutoscalingAnnotation holds, on a node, the usage of its volumes that are
+	// used above the autoscaling threshold of their LocalStorageClass, as JSON
+	// keyed by volume ID. The node plugin reports it, the controller raises the
+	// requests of the claims.
+	AutoscalingAnnotation = "local.csi.storage.deckhouse.io/autoscaling"
+
+	// XFSFeatureLabelPrefix prefixes the labels of the nodes telling whether a
+	// node formats and mounts an xfs feature, such as
+	// local.csi.storage.deckhouse.io/xfs-reflink. They are informational: no
+	// volume is placed by them.
+	XFSFeatureLabelPrefix = "local.csi.storage.deckhouse.io/xfs-"
+
 	// supported filesystem types
-	FSTypeExt4 = "ext4"
-	FSTypeXfs  = "xfs"
+	FSTypeExt4  = "ext4"
+	FSTypeExt3  = "ext3"
+	FSTypeXfs   = "xfs"
+	FSTypeBtrfs = "btrfs"
 )
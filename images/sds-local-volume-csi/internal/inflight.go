@@ -18,7 +18,11 @@ limitations under the License.
 package internal
 
 import (
+	"encoding/json"
+	"net/http"
+	"sort"
 	"sync"
+	"time"
 
 	"k8s.io/klog/v2"
 )
@@ -36,23 +40,47 @@ const (
 	VolumeOperationAlreadyExistsErrorMsg = "An operation with the given Volume %s already exists"
 )
 
+// Operation describes who holds an in-flight key: the RPC that took it, the
+// trace ID it logs under and when it started. It is what turns an Aborted
+// response into something that can be looked up in the logs.
+type Operation struct {
+	Key     string    `json:"key"`
+	RPC     string    `json:"rpc"`
+	TraceID string    `json:"traceID"`
+	Started time.Time `json:"started"`
+}
+
+// Age returns how long the operation has been running at now.
+func (o Operation) Age(now time.Time) time.Duration {
+	return now.Sub(o.Started)
+}
+
 // InFlight is a struct used to manage in flight requests for a unique identifier.
 type InFlight struct {
 	mux      *sync.Mutex
-	inFlight map[string]bool
+	inFlight map[string]Operation
+	now      func() time.Time
 }
 
 // NewInFlight instanciates a InFlight structures.
 func NewInFlight() *InFlight {
 	return &InFlight{
 		mux:      &sync.Mutex{},
-		inFlight: make(map[string]bool),
+		inFlight: make(map[string]Operation),
+		now:      time.Now,
 	}
 }
 
 // Insert inserts the entry to the current list of inflight, request key is a unique identifier.
 // Returns false when the key already exists.
 func (db *InFlight) Insert(key string) bool {
+	return db.InsertOperation(key, "", "")
+}
+
+// InsertOperation is Insert that also records the RPC and the trace ID the key
+// is taken for. Returns false when the key already exists; Get then tells who
+// holds it.
+func (db *InFlight) InsertOperation(key, rpc, traceID string) bool {
 	db.mux.Lock()
 	defer db.mux.Unlock()
 
@@ -61,10 +89,24 @@ func (db *InFlight) Insert(key string) bool {
 		return false
 	}
 
-	db.inFlight[key] = true
+	db.inFlight[key] = Operation{
+		Key:     key,
+		RPC:     rpc,
+		TraceID: traceID,
+		Started: db.now(),
+	}
 	return true
 }
 
+// Get returns the operation holding key, if any.
+func (db *InFlight) Get(key string) (Operation, bool) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	op, ok := db.inFlight[key]
+	return op, ok
+}
+
 // Delete removes the entry from the inFlight entries map.
 // It doesn't return anything, and will do nothing if the specified key doesn't exist.
 func (db *InFlight) Delete(key string) {
@@ -74,3 +116,76 @@ func (db *InFlight) Delete(key string) {
 	delete(db.inFlight, key)
 	klog.V(4).InfoS("Node Service: volume operation finished", "key", key)
 }
+
+// Keys returns the keys of the operations currently in flight, sorted so that a
+// log line listing them is stable between calls.
+func (db *InFlight) Keys() []string {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	keys := make([]string, 0, len(db.inFlight))
+	for k := range db.inFlight {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Operations returns a snapshot of the in-flight table, oldest first.
+func (db *InFlight) Operations() []Operation {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	ops := make([]Operation, 0, len(db.inFlight))
+	for _, op := range db.inFlight {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Started.Equal(ops[j].Started) {
+			return ops[i].Key < ops[j].Key
+		}
+		return ops[i].Started.Before(ops[j].Started)
+	})
+
+	return ops
+}
+
+// OldestAge returns the age of the longest-running operation, or zero when
+// nothing is in flight. A value that keeps growing is a stuck operation — a hung
+// mkfs in NodeStageVolume, for instance — rather than a slow one.
+func (db *InFlight) OldestAge() time.Duration {
+	ops := db.Operations()
+	if len(ops) == 0 {
+		return 0
+	}
+
+	return ops[0].Age(db.now())
+}
+
+// inFlightEntry is the JSON shape of one row served by ServeHTTP. The age is
+// spelled out so that nobody has to subtract timestamps while debugging.
+type inFlightEntry struct {
+	Operation
+	Age string `json:"age"`
+}
+
+// ServeHTTP writes the current in-flight table as JSON, oldest first. It is
+// mounted on /debug/inflight next to /metrics.
+func (db *InFlight) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	now := db.now()
+	ops := db.Operations()
+
+	entries := make([]inFlightEntry, 0, len(ops))
+	for _, op := range ops {
+		entries = append(entries, inFlightEntry{
+			Operation: op,
+			Age:       op.Age(now).Round(time.Millisecond).String(),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -18,7 +18,14 @@ limitations under the License.
 package internal
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type testRequest struct {
@@ -110,3 +117,68 @@ func TestInFlight(t *testing.T) {
 		})
 	}
 }
+
+func TestInFlightKeys(t *testing.T) {
+	db := NewInFlight()
+	assert.Empty(t, db.Keys())
+
+	db.Insert("vol-b")
+	db.Insert("vol-a")
+	db.Insert("vol-c")
+	db.Delete("vol-c")
+
+	assert.Equal(t, []string{"vol-a", "vol-b"}, db.Keys())
+}
+
+func TestInFlightOperations(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	db := NewInFlight()
+	db.now = func() time.Time { return now }
+
+	assert.Zero(t, db.OldestAge())
+
+	require.True(t, db.InsertOperation("vol-a", "NodeStageVolume", "trace-a"))
+	now = now.Add(time.Second)
+	require.True(t, db.InsertOperation("vol-b", "NodePublishVolume", "trace-b"))
+	now = now.Add(time.Minute)
+
+	t.Run("a_second_insert_reports_the_holder", func(t *testing.T) {
+		assert.False(t, db.InsertOperation("vol-a", "NodeUnstageVolume", "trace-c"))
+
+		op, ok := db.Get("vol-a")
+		require.True(t, ok)
+		assert.Equal(t, "NodeStageVolume", op.RPC)
+		assert.Equal(t, "trace-a", op.TraceID)
+		assert.Equal(t, time.Minute+time.Second, op.Age(now))
+	})
+
+	t.Run("operations_are_listed_oldest_first", func(t *testing.T) {
+		ops := db.Operations()
+		require.Len(t, ops, 2)
+		assert.Equal(t, "vol-a", ops[0].Key)
+		assert.Equal(t, "vol-b", ops[1].Key)
+	})
+
+	t.Run("oldest_age_follows_the_oldest_operation", func(t *testing.T) {
+		assert.Equal(t, time.Minute+time.Second, db.OldestAge())
+
+		db.Delete("vol-a")
+		assert.Equal(t, time.Minute, db.OldestAge())
+	})
+
+	t.Run("serves_the_table_as_json", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		db.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/inflight", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var got []map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		require.Len(t, got, 1)
+		assert.Equal(t, "vol-b", got[0]["key"])
+		assert.Equal(t, "NodePublishVolume", got[0]["rpc"])
+		assert.Equal(t, "trace-b", got[0]["traceID"])
+		assert.Equal(t, "1m0s", got[0]["age"])
+	})
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/qos"
+)
+
+// PublishedVolume is a volume published to a pod on the node.
+type PublishedVolume struct {
+	VolumeID string `json:"volumeID"`
+	PodUID   string `json:"podUID"`
+	// Device is the device the pod uses, the LUKS device of an encrypted
+	// volume, and LVPath the LV underneath.
+	Device string `json:"device"`
+	LVPath string `json:"lvPath"`
+	// Readonly is set for a volume published read-only, whose mount is
+	// expected to be read-only.
+	Readonly bool `json:"readonly,omitempty"`
+	// Parameters are the mutable parameters of the volume context, which the
+	// annotations of the LVMLogicalVolume override.
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// Limits are the IO limits applied last.
+	Limits qos.Limits `json:"limits"`
+}
+
+// PublishedVolumes keeps the published volumes by target path, so that their
+// IO limits and LV tags can be applied again and the limits reported in the
+// metrics. The kubelet does not publish the volumes again after a
+// restart of the plugin, so the set is saved to a file.
+type PublishedVolumes = Registry[PublishedVolume]
+
+// NewPublishedVolumes returns an empty set of published volumes saved to path.
+func NewPublishedVolumes(path string) *PublishedVolumes {
+	return NewRegistry[PublishedVolume](path)
+}
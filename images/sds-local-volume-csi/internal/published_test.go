@@ -0,0 +1,54 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/qos"
+)
+
+func TestPublishedVolumesSurviveARestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "published-volumes.json")
+	volume := PublishedVolume{
+		VolumeID:   "pvc-1",
+		PodUID:     "0c5d3e2a-7f4b-4d0e-9a61-2b8c1f3e4d5a",
+		Device:     "/dev/mapper/luks-pvc-1",
+		LVPath:     "/dev/vg/pvc-1",
+		Parameters: map[string]string{qos.ReadIOPSKey: "100"},
+		Limits:     qos.Limits{ReadIOPS: 100},
+	}
+
+	p := NewPublishedVolumes(path)
+	require.NoError(t, p.Load())
+	require.NoError(t, p.Set("/target/1", volume))
+	require.NoError(t, p.Set("/target/2", volume))
+	require.NoError(t, p.Delete("/target/2"))
+	require.NoError(t, p.Delete("/target/unknown"))
+
+	restarted := NewPublishedVolumes(path)
+	require.NoError(t, restarted.Load())
+	assert.Equal(t, map[string]PublishedVolume{"/target/1": volume}, restarted.List())
+
+	got, ok := restarted.Get("/target/1")
+	assert.True(t, ok)
+	assert.Equal(t, volume, got)
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Registry keeps the volumes the node plugin works with by a key, a path or a
+// volume ID. It is saved to a file on every change and survives a restart of
+// the plugin.
+type Registry[V any] struct {
+	path string
+
+	mu      sync.Mutex // protects volumes
+	volumes map[string]V
+}
+
+// NewRegistry returns an empty registry saved to path.
+func NewRegistry[V any](path string) *Registry[V] {
+	return &Registry[V]{path: path, volumes: map[string]V{}}
+}
+
+// Load reads the volumes saved by an earlier run. A missing file is none.
+func (r *Registry[V]) Load() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to read %s: %w", r.path, err)
+	}
+
+	volumes := map[string]V{}
+	if err := json.Unmarshal(data, &volumes); err != nil {
+		return fmt.Errorf("unable to parse %s: %w", r.path, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.volumes = volumes
+
+	return nil
+}
+
+// Get returns the volume recorded under key.
+func (r *Registry[V]) Get(key string) (V, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	volume, ok := r.volumes[key]
+	return volume, ok
+}
+
+// List returns a copy of the volumes by key.
+func (r *Registry[V]) List() map[string]V {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return maps.Clone(r.volumes)
+}
+
+// Set records the volume under key.
+func (r *Registry[V]) Set(key string, volume V) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.volumes[key] = volume
+	return r.save()
+}
+
+// Delete forgets a key. An unknown one is not an error.
+func (r *Registry[V]) Delete(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.volumes[key]; !ok {
+		return nil
+	}
+
+	delete(r.volumes, key)
+	return r.save()
+}
+
+// save writes the volumes to a temporary file renamed over the state file,
+// so that a crash never leaves a half-written one behind. r.mu must be held.
+func (r *Registry[V]) save() error {
+	data, err := json.Marshal(r.volumes)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to save %s: %w", r.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to save %s: %w", r.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to save %s: %w", r.path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("unable to save %s: %w", r.path, err)
+	}
+
+	return nil
+}
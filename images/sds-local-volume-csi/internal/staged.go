@@ -0,0 +1,63 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import "time"
+
+// StagedVolume is a volume staged on the node, which the node plugin collects
+// the usage metrics of.
+type StagedVolume struct {
+	StagingPath string `json:"stagingPath"`
+	// Block is set for a block volume, which has no filesystem to stat.
+	Block bool `json:"block,omitempty"`
+	// Device is the device the volume is used through, the LUKS device of an
+	// encrypted volume, and LVPath the LV underneath.
+	Device string `json:"device"`
+	LVPath string `json:"lvPath"`
+	// LVUUID is the UUID of the LV, which the device of the volume is looked
+	// up by while it stays staged.
+	LVUUID string `json:"lvUUID,omitempty"`
+
+	// The claim, the class and the place of the volume, for the metric labels.
+	PVCName           string `json:"pvcName,omitempty"`
+	PVCNamespace      string `json:"pvcNamespace,omitempty"`
+	LocalStorageClass string `json:"localStorageClass,omitempty"`
+	VGName            string `json:"vgName"`
+	ThinPoolName      string `json:"thinPoolName,omitempty"`
+	// ReadOnly is set for a snapshot mounted read-only, which is neither
+	// trimmed nor autoscaled.
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// Parameters are the mutable parameters of the volume context, which the
+	// annotations of the LVMLogicalVolume override.
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// The periodic discard of a thin volume with a filesystem. TrimInterval
+	// is zero for a volume that is not trimmed.
+	TrimInterval    time.Duration `json:"trimInterval,omitempty"`
+	TrimConcurrency int           `json:"trimConcurrency,omitempty"`
+	LastTrim        time.Time     `json:"lastTrim"`
+}
+
+// StagedVolumes keeps the staged volumes by volume ID. The kubelet does not
+// stage the volumes again after a restart of the plugin, so the set is saved
+// to a file.
+type StagedVolumes = Registry[StagedVolume]
+
+// NewStagedVolumes returns an empty set of staged volumes saved to path.
+func NewStagedVolumes(path string) *StagedVolumes {
+	return NewRegistry[StagedVolume](path)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package encryption manages the Secrets holding the LUKS passphrases of the
+// volumes of a LocalStorageClass with per-volume keys.
+//
+// The node plugin never reads these Secrets itself: the StorageClass names
+// them in its node-stage and node-expand secret parameters, and the kubelet
+// passes them to NodeStageVolume and NodeExpandVolume.
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ModeKey is the StorageClass parameter that marks an encrypted class.
+	ModeKey = "local.csi.storage.deckhouse.io/encryption"
+	// Values of ModeKey.
+	ModeSharedKey    = "SharedKey"
+	ModePerVolumeKey = "PerVolumeKey"
+
+	// SecretKey is the field of the Secret holding the passphrase.
+	SecretKey = "key"
+
+	// SecretNamePrefix and SecretNamespace place the per-volume Secrets. The
+	// controller, which writes them into the StorageClass, states them too.
+	SecretNamePrefix = "sds-local-volume-luks-"
+	SecretNamespace  = "d8-sds-local-volume"
+
+	// passphraseBytes is the entropy of a generated passphrase, as many bytes
+	// as the 512-bit key of aes-xts-plain64 that LUKS2 uses by default.
+	passphraseBytes = 64
+
+	// volumeIDLabel names the volume or the snapshot a Secret belongs to.
+	volumeIDLabel = "storage.deckhouse.io/volume-id"
+)
+
+// SecretName returns the name of the Secret holding the passphrase of a
+// volume or a snapshot.
+func SecretName(id string) string {
+	return SecretNamePrefix + id
+}
+
+// GenerateSecret creates the Secret of the volume id holding a new random
+// passphrase. A Secret left over by an earlier attempt is kept: the volume may
+// have been formatted with it already.
+func GenerateSecret(ctx context.Context, cl client.Client, id string) error {
+	passphrase := make([]byte, passphraseBytes)
+	if _, err := rand.Read(passphrase); err != nil {
+		return fmt.Errorf("unable to generate a passphrase: %w", err)
+	}
+
+	return createSecret(ctx, cl, id, []byte(hex.EncodeToString(passphrase)))
+}
+
+// CopySecret creates the Secret of id holding the passphrase of fromID, for a
+// volume made from a snapshot or a clone, and a snapshot of a volume. It
+// reports false if fromID has no Secret.
+func CopySecret(ctx context.Context, cl client.Client, id, fromID string) (bool, error) {
+	source := &corev1.Secret{}
+	if err := cl.Get(ctx, client.ObjectKey{Namespace: SecretNamespace, Name: SecretName(fromID)}, source); err != nil {
+		if kerrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to get the Secret of %s: %w", fromID, err)
+	}
+
+	passphrase, ok := source.Data[SecretKey]
+	if !ok {
+		return false, fmt.Errorf("the Secret %s/%s has no %q", SecretNamespace, source.Name, SecretKey)
+	}
+
+	return true, createSecret(ctx, cl, id, passphrase)
+}
+
+// DeleteSecret deletes the Secret of id. A missing Secret is not an error: the
+// volume or the snapshot may not have one.
+func DeleteSecret(ctx context.Context, cl client.Client, id string) error {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: SecretNamespace, Name: SecretName(id)}}
+	if err := cl.Delete(ctx, secret); err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("unable to delete the Secret of %s: %w", id, err)
+	}
+
+	return nil
+}
+
+func createSecret(ctx context.Context, cl client.Client, id string, passphrase []byte) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: SecretNamespace,
+			Name:      SecretName(id),
+			Labels:    map[string]string{volumeIDLabel: id},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{SecretKey: passphrase},
+	}
+
+	if err := cl.Create(ctx, secret); err != nil && !kerrors.IsAlreadyExists(err) {
+		return fmt.Errorf("unable to create the Secret of %s: %w", id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func getPassphrase(t *testing.T, cl client.Client, id string) []byte {
+	t.Helper()
+
+	secret := &corev1.Secret{}
+	require.NoError(t, cl.Get(context.Background(), client.ObjectKey{Namespace: SecretNamespace, Name: SecretName(id)}, secret))
+	return secret.Data[SecretKey]
+}
+
+func TestGenerateSecret(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().Build()
+
+	require.NoError(t, GenerateSecret(ctx, cl, "pvc-1"))
+	first := getPassphrase(t, cl, "pvc-1")
+	assert.Len(t, first, 2*passphraseBytes)
+
+	// A retried CreateVolume must not replace a passphrase the volume may
+	// already have been formatted with.
+	require.NoError(t, GenerateSecret(ctx, cl, "pvc-1"))
+	assert.Equal(t, first, getPassphrase(t, cl, "pvc-1"))
+
+	require.NoError(t, GenerateSecret(ctx, cl, "pvc-2"))
+	assert.NotEqual(t, first, getPassphrase(t, cl, "pvc-2"))
+}
+
+func TestCopySecret(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().Build()
+	require.NoError(t, GenerateSecret(ctx, cl, "pvc-1"))
+
+	copied, err := CopySecret(ctx, cl, "snapshot-1", "pvc-1")
+	require.NoError(t, err)
+	assert.True(t, copied)
+	assert.Equal(t, getPassphrase(t, cl, "pvc-1"), getPassphrase(t, cl, "snapshot-1"))
+
+	copied, err = CopySecret(ctx, cl, "pvc-3", "pvc-unencrypted")
+	require.NoError(t, err)
+	assert.False(t, copied)
+}
+
+func TestDeleteSecret(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().Build()
+	require.NoError(t, GenerateSecret(ctx, cl, "pvc-1"))
+
+	require.NoError(t, DeleteSecret(ctx, cl, "pvc-1"))
+	err := cl.Get(ctx, client.ObjectKey{Namespace: SecretNamespace, Name: SecretName("pvc-1")}, &corev1.Secret{})
+	assert.True(t, kerrors.IsNotFound(err))
+
+	assert.NoError(t, DeleteSecret(ctx, cl, "pvc-1"))
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package events reports volume failures and automatic expansions as
+// Kubernetes Events on the PersistentVolumeClaim and PersistentVolume they
+// concern, so that they show up in `kubectl describe pvc` rather than in the
+// driver logs only.
+package events
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+)
+
+// Event reasons. They are part of the driver's interface: alerts and scripts
+// select on them, so they never change once released.
+const (
+	ReasonInsufficientSpace          = "InsufficientSpace"
+	ReasonLVGNotFound                = "LVGNotFound"
+	ReasonLVGNotReady                = "LVGNotReady"
+	ReasonDeviceMissing              = "DeviceMissing"
+	ReasonFormatFailed               = "FormatFailed"
+	ReasonVolumeAttributesNotApplied = "VolumeAttributesNotApplied"
+	ReasonVolumeAutoscaled           = "VolumeAutoscaled"
+	ReasonAutoscalingBlocked         = "AutoscalingBlocked"
+	ReasonFilesystemChecked          = "FilesystemChecked"
+	ReasonFilesystemRepaired         = "FilesystemRepaired"
+	ReasonFilesystemCorrupted        = "FilesystemCorrupted"
+	ReasonFilesystemCheckFailed      = "FilesystemCheckFailed"
+	ReasonStaleMount                 = "StaleMount"
+	ReasonUnmountBusy                = "UnmountBusy"
+	ReasonLazyUnmounted              = "LazyUnmounted"
+)
+
+// Spam filter of the event correlator: every object gets a burst of
+// spamBurst events, refilled at one event per spamRefill seconds. The
+// provisioner retries a failed CreateVolume and the kubelet a failed
+// NodeStageVolume every few seconds for as long as the failure lasts.
+const (
+	spamBurst  = 10
+	spamRefill = 60
+)
+
+// Target names the objects an event is about. Any of the fields may be empty.
+type Target struct {
+	PVCName      string
+	PVCNamespace string
+	PVName       string
+}
+
+// TargetFromParameters reads the target from the PVC and PV parameters the
+// external-provisioner adds to CreateVolume. CreateVolume copies its parameters
+// into the volume context, so the node plugin reads them from there.
+func TargetFromParameters(params map[string]string) Target {
+	return Target{
+		PVCName:      params[internal.PVCNameKey],
+		PVCNamespace: params[internal.PVCNamespaceKey],
+		PVName:       params[internal.PVNameKey],
+	}
+}
+
+// Recorder emits Warning events on the PVC and PV of a volume. The zero value
+// is usable and emits nothing, so code paths and tests without an API server
+// need no special casing.
+type Recorder struct {
+	cl       client.Client
+	recorder record.EventRecorder
+	log      logger.Logger
+}
+
+// NewRecorder returns a Recorder sending events through sink and the function
+// stopping it. The events are deduplicated and rate-limited by the client-go
+// event correlator: a repeated event bumps the count of the existing one
+// rather than creating another, and an object flooded with events gets
+// spamBurst of them and then one per spamRefill seconds.
+func NewRecorder(cl client.Client, sink typedcorev1.EventInterface, component, host string, log logger.Logger) (Recorder, func()) {
+	broadcaster := record.NewBroadcaster(record.WithCorrelatorOptions(record.CorrelatorOptions{
+		BurstSize: spamBurst,
+		QPS:       1.0 / spamRefill,
+	}))
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: sink})
+
+	recorder := broadcaster.NewRecorder(cl.Scheme(), corev1.EventSource{Component: component, Host: host})
+
+	return NewRecorderFor(cl, recorder, log), broadcaster.Shutdown
+}
+
+// NewRecorderFor returns a Recorder emitting through the given EventRecorder.
+func NewRecorderFor(cl client.Client, recorder record.EventRecorder, log logger.Logger) Recorder {
+	return Recorder{cl: cl, recorder: recorder, log: log.Named("events")}
+}
+
+// Warning emits a Warning event with the given reason on every object of
+// target that exists. A PV bound to a claim also brings that claim in, which
+// covers the callers that know the volume only.
+func (r Recorder) Warning(ctx context.Context, target Target, reason, messageFmt string, args ...interface{}) {
+	r.emit(ctx, target, corev1.EventTypeWarning, reason, messageFmt, args...)
+}
+
+// Normal emits a Normal event with the given reason the way Warning does.
+func (r Recorder) Normal(ctx context.Context, target Target, reason, messageFmt string, args ...interface{}) {
+	r.emit(ctx, target, corev1.EventTypeNormal, reason, messageFmt, args...)
+}
+
+func (r Recorder) emit(ctx context.Context, target Target, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.recorder == nil {
+		return
+	}
+
+	for _, obj := range r.resolve(ctx, target) {
+		r.recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+	}
+}
+
+// resolve fetches the objects of target. The events need their UIDs, without
+// which `kubectl describe` does not list them. An object that cannot be fetched
+// is skipped: during CreateVolume the PV, for one, does not exist yet.
+func (r Recorder) resolve(ctx context.Context, target Target) []runtime.Object {
+	var objs []runtime.Object
+
+	if target.PVName != "" {
+		pv := &corev1.PersistentVolume{}
+		if err := r.cl.Get(ctx, client.ObjectKey{Name: target.PVName}, pv); err != nil {
+			r.log.Debug("unable to get the PersistentVolume, not emitting the event on it", "pvName", target.PVName, "error", err.Error())
+		} else {
+			objs = append(objs, pv)
+
+			if target.PVCName == "" && pv.Spec.ClaimRef != nil {
+				target.PVCName = pv.Spec.ClaimRef.Name
+				target.PVCNamespace = pv.Spec.ClaimRef.Namespace
+			}
+		}
+	}
+
+	if target.PVCName != "" && target.PVCNamespace != "" {
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := r.cl.Get(ctx, client.ObjectKey{Namespace: target.PVCNamespace, Name: target.PVCName}, pvc); err != nil {
+			r.log.Debug("unable to get the PersistentVolumeClaim, not emitting the event on it", "pvcName", target.PVCName, "pvcNamespace", target.PVCNamespace, "error", err.Error())
+		} else {
+			objs = append(objs, pvc)
+		}
+	}
+
+	return objs
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
+)
+
+func newTestRecorder(t *testing.T) (Recorder, *record.FakeRecorder) {
+	t.Helper()
+
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "app"}}
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+		Spec: corev1.PersistentVolumeSpec{
+			ClaimRef: &corev1.ObjectReference{Name: "data", Namespace: "app"},
+		},
+	}
+	cl := fake.NewClientBuilder().WithObjects(pvc, pv).Build()
+
+	log, err := logger.NewLogger(logger.DebugLevel)
+	require.NoError(t, err)
+
+	fakeRecorder := record.NewFakeRecorder(10)
+	return NewRecorderFor(cl, fakeRecorder, log), fakeRecorder
+}
+
+func drain(r *record.FakeRecorder) []string {
+	var got []string
+	for {
+		select {
+		case e := <-r.Events:
+			got = append(got, e)
+		default:
+			return got
+		}
+	}
+}
+
+func TestWarningFromParameters(t *testing.T) {
+	r, fakeRecorder := newTestRecorder(t)
+
+	// During CreateVolume the PV does not exist yet: only the claim gets the event.
+	target := TargetFromParameters(map[string]string{
+		internal.PVCNameKey:      "data",
+		internal.PVCNamespaceKey: "app",
+		internal.PVNameKey:       "pvc-2",
+	})
+	r.Warning(context.Background(), target, ReasonInsufficientSpace, "requested %s, free %s", "10Gi", "1Gi")
+
+	assert.Equal(t, []string{"Warning InsufficientSpace requested 10Gi, free 1Gi"}, drain(fakeRecorder))
+}
+
+func TestWarningFromPVName(t *testing.T) {
+	r, fakeRecorder := newTestRecorder(t)
+
+	r.Warning(context.Background(), Target{PVName: "pvc-1"}, ReasonDeviceMissing, "device is missing")
+
+	// the PV and the claim it is bound to
+	assert.Equal(t, []string{
+		"Warning DeviceMissing device is missing",
+		"Warning DeviceMissing device is missing",
+	}, drain(fakeRecorder))
+}
+
+func TestNormal(t *testing.T) {
+	r, fakeRecorder := newTestRecorder(t)
+
+	r.Normal(context.Background(), Target{PVCName: "data", PVCNamespace: "app"}, ReasonVolumeAutoscaled, "expanding to %s", "20Gi")
+
+	assert.Equal(t, []string{"Normal VolumeAutoscaled expanding to 20Gi"}, drain(fakeRecorder))
+}
+
+func TestWarningZeroValue(t *testing.T) {
+	assert.NotPanics(t, func() {
+		Recorder{}.Warning(context.Background(), Target{PVName: "pvc-1"}, ReasonFormatFailed, "mkfs failed")
+	})
+}
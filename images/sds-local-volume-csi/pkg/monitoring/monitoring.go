@@ -39,14 +39,59 @@ import (
 const (
 	OperationsTotal          = "sds_local_volume_csi_operations_total"
 	OperationDurationSeconds = "sds_local_volume_csi_operation_duration_seconds"
+	InFlightOldestAgeSeconds = "sds_local_volume_csi_inflight_oldest_operation_age_seconds"
+	AbandonedOperationsTotal = "sds_local_volume_csi_abandoned_operations_total"
+
+	VolumeCapacityBytes  = "sds_local_volume_csi_volume_capacity_bytes"
+	VolumeUsedBytes      = "sds_local_volume_csi_volume_used_bytes"
+	VolumeAvailableBytes = "sds_local_volume_csi_volume_available_bytes"
+	VolumeIOLimit        = "sds_local_volume_csi_volume_io_limit"
+
+	ThinPoolTrimmedBytesTotal = "sds_local_volume_csi_thin_pool_trimmed_bytes_total"
+	ThinPoolTrimFailuresTotal = "sds_local_volume_csi_thin_pool_trim_failures_total"
+
+	FsckTotal = "sds_local_volume_csi_fsck_total"
+
+	StaleMounts             = "sds_local_volume_csi_stale_mounts"
+	StaleMountsCleanedTotal = "sds_local_volume_csi_stale_mounts_cleaned_total"
 )
 
 // Label names.
 const (
 	LabelMethod   = "method"
 	LabelGRPCCode = "grpc_code"
+
+	LabelNamespace         = "namespace"
+	LabelPVC               = "persistentvolumeclaim"
+	LabelLocalStorageClass = "local_storage_class"
+	LabelVolumeGroup       = "volume_group"
+	LabelThinPool          = "thin_pool"
+
+	LabelFSType = "fs_type"
+	LabelResult = "result"
+	LabelLimit  = "limit"
+)
+
+// Values of LabelLimit.
+const (
+	LimitReadIOPS  = "read_iops"
+	LimitWriteIOPS = "write_iops"
+	LimitReadBPS   = "read_bytes_per_second"
+	LimitWriteBPS  = "write_bytes_per_second"
 )
 
+// volumeLabels are the labels of the volume usage metrics. They name the claim
+// and where the volume lives but neither the pod nor the node path, so that
+// there is one series per staged volume however many pods use it.
+var volumeLabels = []string{LabelLocalStorageClass, LabelNamespace, LabelPVC, LabelThinPool, LabelVolumeGroup}
+
+// volumeMetrics are the volume usage metrics with their help.
+var volumeMetrics = map[string]string{
+	VolumeCapacityBytes:  "Size of a staged volume, in bytes.",
+	VolumeUsedBytes:      "Bytes used on a staged volume: by its filesystem, or mapped in its thin pool for a thin block volume.",
+	VolumeAvailableBytes: "Bytes still available to a staged volume, capped by the free space of its thin pool for a thin volume.",
+}
+
 // operationDurationBuckets is deliberately wider than a plain HTTP-latency
 // layout: NodeStageVolume formats a filesystem and CreateVolume waits for
 // sds-node-configurator to act on an LVMLogicalVolume, both of which routinely
@@ -76,6 +121,78 @@ func Register(st metricsstorage.Storage) error {
 		return fmt.Errorf("register %s: %w", OperationDurationSeconds, err)
 	}
 
+	if _, err := st.RegisterGauge(
+		InFlightOldestAgeSeconds,
+		[]string{},
+		options.WithHelp("Age of the oldest volume operation holding an in-flight lock, in seconds; 0 when none is in flight."),
+	); err != nil {
+		return fmt.Errorf("register %s: %w", InFlightOldestAgeSeconds, err)
+	}
+
+	if _, err := st.RegisterCounter(
+		AbandonedOperationsTotal,
+		[]string{LabelMethod},
+		options.WithHelp("Volume operations still in flight when the drain on shutdown timed out."),
+	); err != nil {
+		return fmt.Errorf("register %s: %w", AbandonedOperationsTotal, err)
+	}
+
+	if _, err := st.RegisterCounter(
+		ThinPoolTrimmedBytesTotal,
+		[]string{LabelVolumeGroup, LabelThinPool},
+		options.WithHelp("Bytes the periodic discard of the staged thin volumes has returned to their thin pool, as the bytes the volumes have mapped before and after fstrim."),
+	); err != nil {
+		return fmt.Errorf("register %s: %w", ThinPoolTrimmedBytesTotal, err)
+	}
+
+	if _, err := st.RegisterCounter(
+		ThinPoolTrimFailuresTotal,
+		[]string{LabelVolumeGroup, LabelThinPool},
+		options.WithHelp("Failed runs of fstrim on the staged thin volumes of a thin pool."),
+	); err != nil {
+		return fmt.Errorf("register %s: %w", ThinPoolTrimFailuresTotal, err)
+	}
+
+	if _, err := st.RegisterCounter(
+		FsckTotal,
+		[]string{LabelFSType, LabelResult},
+		options.WithHelp("Filesystem checks run before mounting a volume, partitioned by result: clean, repaired, corrupted, skipped or failed."),
+	); err != nil {
+		return fmt.Errorf("register %s: %w", FsckTotal, err)
+	}
+
+	if _, err := st.RegisterGauge(
+		StaleMounts,
+		[]string{},
+		options.WithHelp("Mounts of the driver's volumes the kubelet has no record of, found by the last check of the node."),
+	); err != nil {
+		return fmt.Errorf("register %s: %w", StaleMounts, err)
+	}
+
+	if _, err := st.RegisterCounter(
+		StaleMountsCleanedTotal,
+		[]string{},
+		options.WithHelp("Mounts of deleted volumes the node plugin has unmounted."),
+	); err != nil {
+		return fmt.Errorf("register %s: %w", StaleMountsCleanedTotal, err)
+	}
+
+	// The volume usage metrics are grouped by volume, so that the series of an
+	// unstaged volume go away at once.
+	for name, help := range volumeMetrics {
+		if _, err := st.Grouped().RegisterGauge(name, volumeLabels, options.WithHelp(help)); err != nil {
+			return fmt.Errorf("register %s: %w", name, err)
+		}
+	}
+
+	if _, err := st.Grouped().RegisterGauge(
+		VolumeIOLimit,
+		append([]string{LabelLimit}, volumeLabels...),
+		options.WithHelp("IO limit applied on the node to a published volume, in operations or bytes per second; 0 is no limit."),
+	); err != nil {
+		return fmt.Errorf("register %s: %w", VolumeIOLimit, err)
+	}
+
 	return nil
 }
 
@@ -116,6 +233,149 @@ func (r Recorder) ObserveOperation(fullMethod string, start time.Time, err error
 	})
 }
 
+// SetInFlightOldestAge publishes the age of the oldest in-flight volume
+// operation. Unlike the operation duration, which is only observed once a call
+// returns, this gauge grows while the call is still stuck.
+func (r Recorder) SetInFlightOldestAge(age time.Duration) {
+	if r.st == nil {
+		return
+	}
+
+	r.st.GaugeSet(InFlightOldestAgeSeconds, age.Seconds(), map[string]string{})
+}
+
+// AddAbandonedOperation records an operation of method the drain on shutdown
+// has given up waiting for.
+func (r Recorder) AddAbandonedOperation(method string) {
+	if r.st == nil {
+		return
+	}
+
+	r.st.CounterAdd(AbandonedOperationsTotal, 1, map[string]string{LabelMethod: method})
+}
+
+// VolumeLabels are the label values of the usage metrics of a volume. A thick
+// volume has no thin pool.
+type VolumeLabels struct {
+	Namespace         string
+	PVC               string
+	LocalStorageClass string
+	VolumeGroup       string
+	ThinPool          string
+}
+
+func (l VolumeLabels) labels() map[string]string {
+	return map[string]string{
+		LabelNamespace:         l.Namespace,
+		LabelPVC:               l.PVC,
+		LabelLocalStorageClass: l.LocalStorageClass,
+		LabelVolumeGroup:       l.VolumeGroup,
+		LabelThinPool:          l.ThinPool,
+	}
+}
+
+// VolumeUsage is the usage of a volume in bytes. Used and Available are not
+// known, and not published, for a thick block volume.
+type VolumeUsage struct {
+	Capacity   int64
+	Used       int64
+	Available  int64
+	UsageKnown bool
+}
+
+// SetVolumeUsage publishes the usage of the staged volume volumeID.
+func (r Recorder) SetVolumeUsage(volumeID string, labels VolumeLabels, usage VolumeUsage) {
+	if r.st == nil {
+		return
+	}
+
+	l := labels.labels()
+	r.st.Grouped().GaugeSet(volumeID, VolumeCapacityBytes, float64(usage.Capacity), l)
+	if usage.UsageKnown {
+		r.st.Grouped().GaugeSet(volumeID, VolumeUsedBytes, float64(usage.Used), l)
+		r.st.Grouped().GaugeSet(volumeID, VolumeAvailableBytes, float64(usage.Available), l)
+	}
+}
+
+// VolumeIOLimits are the IO limits of a volume, 0 for no limit.
+type VolumeIOLimits struct {
+	ReadIOPS  int64
+	WriteIOPS int64
+	ReadBPS   int64
+	WriteBPS  int64
+}
+
+// SetVolumeIOLimits sets the VolumeIOLimit series of the published volume
+// volumeID, one per limit. They are grouped by volumeID with the usage series,
+// so DeleteVolumeUsage removes them too.
+func (r Recorder) SetVolumeIOLimits(volumeID string, labels VolumeLabels, limits VolumeIOLimits) {
+	if r.st == nil {
+		return
+	}
+
+	for limit, value := range map[string]int64{
+		LimitReadIOPS:  limits.ReadIOPS,
+		LimitWriteIOPS: limits.WriteIOPS,
+		LimitReadBPS:   limits.ReadBPS,
+		LimitWriteBPS:  limits.WriteBPS,
+	} {
+		l := labels.labels()
+		l[LabelLimit] = limit
+		r.st.Grouped().GaugeSet(volumeID, VolumeIOLimit, float64(value), l)
+	}
+}
+
+// DeleteVolumeUsage removes the usage series of volumeID once it is unstaged.
+func (r Recorder) DeleteVolumeUsage(volumeID string) {
+	if r.st == nil {
+		return
+	}
+	r.st.Grouped().ExpireGroupMetrics(volumeID)
+}
+
+// AddTrimmed records the bytes one run of fstrim has returned to the thin pool
+// pool of the VG vg, which the thin volume no longer maps.
+func (r Recorder) AddTrimmed(vg, pool string, bytes uint64) {
+	if r.st == nil {
+		return
+	}
+	r.st.CounterAdd(ThinPoolTrimmedBytesTotal, float64(bytes), map[string]string{LabelVolumeGroup: vg, LabelThinPool: pool})
+}
+
+// AddTrimFailure records a failed run of fstrim on a volume of the thin pool
+// pool of the VG vg.
+func (r Recorder) AddTrimFailure(vg, pool string) {
+	if r.st == nil {
+		return
+	}
+	r.st.CounterAdd(ThinPoolTrimFailuresTotal, 1, map[string]string{LabelVolumeGroup: vg, LabelThinPool: pool})
+}
+
+// AddFsck records a filesystem check of a fsType volume with the given result.
+func (r Recorder) AddFsck(fsType, result string) {
+	if r.st == nil {
+		return
+	}
+	r.st.CounterAdd(FsckTotal, 1, map[string]string{LabelFSType: fsType, LabelResult: result})
+}
+
+// SetStaleMounts records the number of mounts of unknown use the last check
+// found.
+func (r Recorder) SetStaleMounts(n int) {
+	if r.st == nil {
+		return
+	}
+	r.st.GaugeSet(StaleMounts, float64(n), map[string]string{})
+}
+
+// AddStaleMountCleaned records an orphaned mount unmounted.
+func (r Recorder) AddStaleMountCleaned() {
+	if r.st == nil {
+		return
+	}
+	r.st.CounterAdd(StaleMountsCleanedTotal, 1, map[string]string{})
+}
+
 // ShortMethodName trims the gRPC service prefix from a full method name, so that
 // /csi.v1.Controller/CreateVolume becomes CreateVolume.
 //
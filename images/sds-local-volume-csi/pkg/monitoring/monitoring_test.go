@@ -55,6 +55,8 @@ func newTestRecorder(t *testing.T) (Recorder, func() string) {
 				switch {
 				case m.GetCounter() != nil:
 					sb.WriteString(strconv.FormatFloat(m.GetCounter().GetValue(), 'f', -1, 64))
+				case m.GetGauge() != nil:
+					sb.WriteString(strconv.FormatFloat(m.GetGauge().GetValue(), 'f', -1, 64))
 				case m.GetHistogram() != nil:
 					sb.WriteString(strconv.FormatUint(m.GetHistogram().GetSampleCount(), 10))
 				}
@@ -155,6 +157,107 @@ func TestObserveOperationSeparatesMethods(t *testing.T) {
 	assert.Contains(t, out, OperationsTotal+"{grpc_code=OK,method=NodeStageVolume,}1")
 }
 
+func TestSetInFlightOldestAge(t *testing.T) {
+	rec, scrape := newTestRecorder(t)
+
+	rec.SetInFlightOldestAge(90 * time.Second)
+	assert.Contains(t, scrape(), InFlightOldestAgeSeconds+"{}90")
+
+	// Once the stuck operation finishes the gauge has to drop back, not keep the
+	// last observed age.
+	rec.SetInFlightOldestAge(0)
+	assert.Contains(t, scrape(), InFlightOldestAgeSeconds+"{}0")
+}
+
+func TestAbandonedOperations(t *testing.T) {
+	rec, scrape := newTestRecorder(t)
+
+	rec.AddAbandonedOperation("CreateVolume")
+	rec.AddAbandonedOperation("NodeStageVolume")
+	rec.AddAbandonedOperation("CreateVolume")
+
+	out := scrape()
+	assert.Contains(t, out, AbandonedOperationsTotal+"{method=CreateVolume,}2")
+	assert.Contains(t, out, AbandonedOperationsTotal+"{method=NodeStageVolume,}1")
+}
+
+func TestVolumeUsage(t *testing.T) {
+	rec, scrape := newTestRecorder(t)
+
+	labels := VolumeLabels{Namespace: "db", PVC: "data-0", LocalStorageClass: "fast", VolumeGroup: "vg-1", ThinPool: "pool"}
+	rec.SetVolumeUsage("pvc-1", labels, VolumeUsage{Capacity: 100, Used: 30, Available: 50, UsageKnown: true})
+	// A thick block volume only has a size.
+	rec.SetVolumeUsage("pvc-2", VolumeLabels{Namespace: "db", PVC: "data-1", LocalStorageClass: "thick", VolumeGroup: "vg-1"}, VolumeUsage{Capacity: 200})
+
+	out := scrape()
+	series := "{local_storage_class=fast,namespace=db,persistentvolumeclaim=data-0,thin_pool=pool,volume_group=vg-1,}"
+	assert.Contains(t, out, VolumeCapacityBytes+series+"100")
+	assert.Contains(t, out, VolumeUsedBytes+series+"30")
+	assert.Contains(t, out, VolumeAvailableBytes+series+"50")
+	assert.Contains(t, out, VolumeCapacityBytes+"{local_storage_class=thick,namespace=db,persistentvolumeclaim=data-1,thin_pool=,volume_group=vg-1,}200")
+	assert.NotContains(t, out, "persistentvolumeclaim=data-1,thin_pool=,volume_group=vg-1,}0")
+
+	// An unstaged volume must not leave its series behind.
+	rec.DeleteVolumeUsage("pvc-1")
+	out = scrape()
+	assert.NotContains(t, out, "persistentvolumeclaim=data-0")
+	assert.Contains(t, out, "persistentvolumeclaim=data-1")
+}
+
+func TestVolumeIOLimits(t *testing.T) {
+	rec, scrape := newTestRecorder(t)
+
+	labels := VolumeLabels{Namespace: "db", PVC: "data-0", LocalStorageClass: "fast", VolumeGroup: "vg-1"}
+	rec.SetVolumeIOLimits("pvc-1", labels, VolumeIOLimits{WriteIOPS: 500, ReadBPS: 1048576})
+
+	out := scrape()
+	series := func(limit string) string {
+		return VolumeIOLimit + "{limit=" + limit + ",local_storage_class=fast,namespace=db,persistentvolumeclaim=data-0,thin_pool=,volume_group=vg-1,}"
+	}
+	assert.Contains(t, out, series(LimitWriteIOPS)+"500")
+	assert.Contains(t, out, series(LimitReadBPS)+"1048576")
+	assert.Contains(t, out, series(LimitReadIOPS)+"0")
+
+	// The limits go with the usage series of an unstaged volume.
+	rec.DeleteVolumeUsage("pvc-1")
+	assert.NotContains(t, scrape(), "persistentvolumeclaim=data-0")
+}
+
+func TestTrimmed(t *testing.T) {
+	rec, scrape := newTestRecorder(t)
+
+	rec.AddTrimmed("vg-1", "pool", 1024)
+	rec.AddTrimmed("vg-1", "pool", 2048)
+	rec.AddTrimFailure("vg-1", "pool")
+
+	out := scrape()
+	assert.Contains(t, out, ThinPoolTrimmedBytesTotal+"{thin_pool=pool,volume_group=vg-1,}3072")
+	assert.Contains(t, out, ThinPoolTrimFailuresTotal+"{thin_pool=pool,volume_group=vg-1,}1")
+}
+
+func TestFsck(t *testing.T) {
+	rec, scrape := newTestRecorder(t)
+
+	rec.AddFsck("ext4", "clean")
+	rec.AddFsck("ext4", "clean")
+	rec.AddFsck("xfs", "corrupted")
+
+	out := scrape()
+	assert.Contains(t, out, FsckTotal+"{fs_type=ext4,result=clean,}2")
+	assert.Contains(t, out, FsckTotal+"{fs_type=xfs,result=corrupted,}1")
+}
+
+func TestStaleMounts(t *testing.T) {
+	rec, scrape := newTestRecorder(t)
+
+	rec.SetStaleMounts(2)
+	rec.AddStaleMountCleaned()
+
+	out := scrape()
+	assert.Contains(t, out, StaleMounts+"{}2")
+	assert.Contains(t, out, StaleMountsCleanedTotal+"{}1")
+}
+
 func TestZeroRecorderRecordsNothing(t *testing.T) {
 	var rec Recorder
 
@@ -162,5 +265,13 @@ func TestZeroRecorderRecordsNothing(t *testing.T) {
 	// panic.
 	assert.NotPanics(t, func() {
 		rec.ObserveOperation("/csi.v1.Controller/CreateVolume", time.Now(), nil)
+		rec.SetInFlightOldestAge(time.Minute)
+		rec.SetVolumeUsage("pvc-1", VolumeLabels{}, VolumeUsage{Capacity: 1})
+		rec.DeleteVolumeUsage("pvc-1")
+		rec.AddTrimmed("vg-1", "pool", 1)
+		rec.AddTrimFailure("vg-1", "pool")
+		rec.AddFsck("ext4", "clean")
+		rec.SetStaleMounts(1)
+		rec.AddStaleMountCleaned()
 	})
 }
@@ -0,0 +1,143 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package qos
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrPodCgroupNotFound is returned when a pod has no cgroup on the node, which
+// is the case for a pod that is gone.
+var ErrPodCgroupNotFound = errors.New("the cgroup of the pod is not found")
+
+// kubepodsCgroups are the cgroups the kubelet nests pod cgroups in, with the
+// cgroupfs driver and with the systemd one: kubepods for Guaranteed pods and
+// one level below it for Burstable and BestEffort ones.
+var kubepodsCgroups = map[string]bool{
+	"kubepods":                  true,
+	"burstable":                 true,
+	"besteffort":                true,
+	"kubepods.slice":            true,
+	"kubepods-burstable.slice":  true,
+	"kubepods-besteffort.slice": true,
+}
+
+// PodUIDFromTargetPath returns the UID of the pod a NodePublishVolume target
+// path belongs to. The kubelet publishes filesystem volumes under
+// /var/lib/kubelet/pods/<uid>/volumes and block volumes at
+// /var/lib/kubelet/plugins/kubernetes.io/csi/volumeDevices/publish/<pv>/<uid>.
+func PodUIDFromTargetPath(target string) (string, bool) {
+	parts := strings.Split(filepath.Clean(target), "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "pods" {
+			return parts[i+1], true
+		}
+	}
+
+	n := len(parts)
+	if n >= 4 && parts[n-4] == "volumeDevices" && parts[n-3] == "publish" {
+		return parts[n-1], true
+	}
+
+	return "", false
+}
+
+// FindPodCgroup returns the cgroup directory of a pod under the cgroup v2
+// root. The kubelet names it pod<uid> with the cgroupfs driver and
+// kubepods-<qos>-pod<uid with underscores>.slice with the systemd one.
+func FindPodCgroup(root, podUID string) (string, error) {
+	cgroupfsName := "pod" + podUID
+	systemdSuffix := "-pod" + strings.ReplaceAll(podUID, "-", "_") + ".slice"
+
+	var find func(dir string) (string, error)
+	find = func(dir string) (string, error) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return "", err
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() && (name == cgroupfsName || strings.HasSuffix(name, systemdSuffix)) {
+				return filepath.Join(dir, name), nil
+			}
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() || !kubepodsCgroups[entry.Name()] {
+				continue
+			}
+			found, err := find(filepath.Join(dir, entry.Name()))
+			if err != nil || found != "" {
+				return found, err
+			}
+		}
+
+		return "", nil
+	}
+
+	dir, err := find(root)
+	if err != nil {
+		return "", fmt.Errorf("unable to look for the cgroup of the pod %s under %s: %w", podUID, root, err)
+	}
+	if dir == "" {
+		return "", fmt.Errorf("%w: %s", ErrPodCgroupNotFound, podUID)
+	}
+
+	return dir, nil
+}
+
+// Apply sets limits on device in the cgroup of a pod. The limits of a pod
+// cgroup cover every container in it, including the ones restarted later.
+func Apply(root, podUID, device string, limits Limits) error {
+	var st unix.Stat_t
+	if err := unix.Stat(device, &st); err != nil {
+		return fmt.Errorf("unable to stat %s: %w", device, err)
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFBLK {
+		return fmt.Errorf("%s is not a block device", device)
+	}
+
+	dir, err := FindPodCgroup(root, podUID)
+	if err != nil {
+		return err
+	}
+
+	return writeIOMax(dir, limits.IOMax(unix.Major(st.Rdev), unix.Minor(st.Rdev)))
+}
+
+func writeIOMax(dir, line string) error {
+	path := filepath.Join(dir, "io.max")
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s has no io.max, the io controller is not enabled in the cgroup or the node runs cgroup v1", dir)
+		}
+		return err
+	}
+
+	if err := os.WriteFile(path, []byte(line), 0); err != nil {
+		return fmt.Errorf("unable to write %q to %s: %w", line, path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package qos
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPodUID = "0c5d3e2a-7f4b-4d0e-9a61-2b8c1f3e4d5a"
+
+func TestPodUIDFromTargetPath(t *testing.T) {
+	for target, want := range map[string]string{
+		"/var/lib/kubelet/pods/" + testPodUID + "/volumes/kubernetes.io~csi/pvc-1/mount":            testPodUID,
+		"/var/lib/kubelet/plugins/kubernetes.io/csi/volumeDevices/publish/pvc-1/" + testPodUID:      testPodUID,
+		"/var/lib/kubelet/plugins/kubernetes.io/csi/local.csi.storage.deckhouse.io/abc/globalmount": "",
+	} {
+		got, ok := PodUIDFromTargetPath(target)
+		assert.Equal(t, want, got, target)
+		assert.Equal(t, want != "", ok, target)
+	}
+}
+
+func TestFindPodCgroup(t *testing.T) {
+	for name, podDir := range map[string]string{
+		"cgroupfs guaranteed": "kubepods/pod" + testPodUID,
+		"cgroupfs burstable":  "kubepods/burstable/pod" + testPodUID,
+		"systemd besteffort":  "kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod0c5d3e2a_7f4b_4d0e_9a61_2b8c1f3e4d5a.slice",
+	} {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(root, podDir, "container"), 0o755))
+			require.NoError(t, os.MkdirAll(filepath.Join(root, "system.slice", "pod"+testPodUID), 0o755))
+
+			got, err := FindPodCgroup(root, testPodUID)
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(root, podDir), got)
+		})
+	}
+
+	_, err := FindPodCgroup(t.TempDir(), testPodUID)
+	assert.True(t, errors.Is(err, ErrPodCgroupNotFound))
+}
+
+func TestWriteIOMax(t *testing.T) {
+	dir := t.TempDir()
+	assert.Error(t, writeIOMax(dir, "253:4 riops=100"), "a cgroup without io.max")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "io.max"), nil, 0o644))
+	require.NoError(t, writeIOMax(dir, "253:4 riops=100"))
+
+	data, err := os.ReadFile(filepath.Join(dir, "io.max"))
+	require.NoError(t, err)
+	assert.Equal(t, "253:4 riops=100", string(data))
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package qos limits the IO of a volume through the cgroup v2 io.max of the
+// pods using it.
+//
+// The limits come from the StorageClass, which the controller fills from the
+// LocalStorageClass, and from the VolumeAttributesClass of the claim, which
+// overrides them key by key. CreateVolume resolves the two into the volume
+// context and the node plugin applies what it finds there.
+package qos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Parameters holding the limits. The controller writes them into the
+// StorageClass, see images/controller/pkg/controller.
+const (
+	ReadIOPSKey  = "local.csi.storage.deckhouse.io/read-iops"
+	WriteIOPSKey = "local.csi.storage.deckhouse.io/write-iops"
+	ReadBPSKey   = "local.csi.storage.deckhouse.io/read-bytes-per-second"
+	WriteBPSKey  = "local.csi.storage.deckhouse.io/write-bytes-per-second"
+)
+
+// Keys lists the parameters holding the limits.
+var Keys = []string{ReadIOPSKey, WriteIOPSKey, ReadBPSKey, WriteBPSKey}
+
+// IsKey reports whether key is one of Keys.
+func IsKey(key string) bool {
+	for _, k := range Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Limits are the IO limits of a volume. Zero is no limit.
+type Limits struct {
+	ReadIOPS  int64 `json:"readIOPS,omitempty"`
+	WriteIOPS int64 `json:"writeIOPS,omitempty"`
+	ReadBPS   int64 `json:"readBPS,omitempty"`
+	WriteBPS  int64 `json:"writeBPS,omitempty"`
+}
+
+// IsZero reports whether l limits nothing.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// With returns l with the limits set in params replacing its own. Values are
+// non-negative integers or Kubernetes quantities such as "100Mi" and "5k";
+// "0" removes a limit. Parameters other than Keys are ignored.
+func (l Limits) With(params map[string]string) (Limits, error) {
+	for key, field := range map[string]*int64{
+		ReadIOPSKey:  &l.ReadIOPS,
+		WriteIOPSKey: &l.WriteIOPS,
+		ReadBPSKey:   &l.ReadBPS,
+		WriteBPSKey:  &l.WriteBPS,
+	} {
+		value, ok := params[key]
+		if !ok {
+			continue
+		}
+
+		parsed, err := parse(value)
+		if err != nil {
+			return Limits{}, fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+		*field = parsed
+	}
+
+	return l, nil
+}
+
+func parse(value string) (int64, error) {
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := q.AsInt64()
+	if !ok {
+		return 0, fmt.Errorf("not an integer")
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("negative")
+	}
+
+	return n, nil
+}
+
+// Parameters returns the parameters holding l, leaving out the limits that are
+// not set. With on the result gives l back.
+func (l Limits) Parameters() map[string]string {
+	params := make(map[string]string, len(Keys))
+	for key, value := range map[string]int64{
+		ReadIOPSKey:  l.ReadIOPS,
+		WriteIOPSKey: l.WriteIOPS,
+		ReadBPSKey:   l.ReadBPS,
+		WriteBPSKey:  l.WriteBPS,
+	} {
+		if value > 0 {
+			params[key] = strconv.FormatInt(value, 10)
+		}
+	}
+
+	return params
+}
+
+// IOMax returns the io.max line setting l on the device major:minor. The limits
+// that are not set are written as "max", which clears them.
+func (l Limits) IOMax(major, minor uint32) string {
+	return fmt.Sprintf("%d:%d %s", major, minor, l)
+}
+
+// String formats l the way io.max does, for logs and messages.
+func (l Limits) String() string {
+	return strings.Join([]string{
+		"rbps=" + ioMaxValue(l.ReadBPS),
+		"wbps=" + ioMaxValue(l.WriteBPS),
+		"riops=" + ioMaxValue(l.ReadIOPS),
+		"wiops=" + ioMaxValue(l.WriteIOPS),
+	}, " ")
+}
+
+func ioMaxValue(value int64) string {
+	if value <= 0 {
+		return "max"
+	}
+	return strconv.FormatInt(value, 10)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package qos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWith(t *testing.T) {
+	class, err := Limits{}.With(map[string]string{
+		ReadIOPSKey:  "1000",
+		WriteIOPSKey: "500",
+		ReadBPSKey:   "104857600",
+		"other":      "ignored",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Limits{ReadIOPS: 1000, WriteIOPS: 500, ReadBPS: 100 << 20}, class)
+
+	// A VolumeAttributesClass overrides the class key by key and removes a
+	// limit with "0".
+	volume, err := class.With(map[string]string{WriteIOPSKey: "2k", ReadBPSKey: "0", WriteBPSKey: "50Mi"})
+	require.NoError(t, err)
+	assert.Equal(t, Limits{ReadIOPS: 1000, WriteIOPS: 2000, WriteBPS: 50 << 20}, volume)
+
+	for _, value := range []string{"-1", "fast", "1.5"} {
+		_, err := Limits{}.With(map[string]string{ReadIOPSKey: value})
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParametersRoundTrip(t *testing.T) {
+	limits := Limits{ReadIOPS: 1000, WriteBPS: 50 << 20}
+
+	params := limits.Parameters()
+	assert.Equal(t, map[string]string{ReadIOPSKey: "1000", WriteBPSKey: "52428800"}, params)
+
+	got, err := Limits{}.With(params)
+	require.NoError(t, err)
+	assert.Equal(t, limits, got)
+}
+
+func TestIOMax(t *testing.T) {
+	assert.Equal(t, "253:4 rbps=max wbps=52428800 riops=1000 wiops=max", Limits{ReadIOPS: 1000, WriteBPS: 50 << 20}.IOMax(253, 4))
+	assert.Equal(t, "253:4 rbps=max wbps=max riops=max wiops=max", Limits{}.IOMax(253, 4))
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing wires OpenTelemetry into the CSI driver.
+//
+// Every CSI RPC gets a server span from the gRPC interceptor, and the long steps
+// inside it — waiting for an LVMLogicalVolume, formatting a device — get child
+// spans through Start. The trace ID doubles as the traceID field of the RPC's
+// log lines, so a span found in the tracing backend leads straight to the logs
+// and the other way round.
+//
+// With no exporter configured the global provider stays the OpenTelemetry
+// no-op one: spans cost next to nothing and TraceID falls back to a random ID,
+// which is what the driver logged before tracing existed.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TracerName is the instrumentation scope of every span of the driver.
+	TracerName = "github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi"
+
+	// TraceParentAnnotationKey carries the W3C traceparent of the CreateVolume
+	// call on the LVMLogicalVolume it created, so that the sds-node-configurator
+	// logs for that resource can be tied back to the trace.
+	TraceParentAnnotationKey = "local.csi.storage.deckhouse.io/traceparent"
+)
+
+// Exporters accepted by Setup.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+var propagator = propagation.TraceContext{}
+
+// Setup installs a global tracer provider sending spans to the given exporter
+// and returns the function flushing and stopping it, to be called on shutdown.
+//
+// endpoint is used by the otlp exporter only. It is a URL such as
+// http://otel-collector:4317; an http scheme selects a plaintext connection.
+// When it is empty the exporter falls back to the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT variables.
+func Setup(ctx context.Context, exporter, endpoint, serviceName string) (func(context.Context) error, error) {
+	var (
+		spanExporter sdktrace.SpanExporter
+		err          error
+	)
+
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var opts []otlptracegrpc.Option
+		if endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpointURL(endpoint))
+		}
+		spanExporter, err = otlptracegrpc.New(ctx, opts...)
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New()
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create the %s exporter: %w", exporter, err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+
+	return tp.Shutdown, nil
+}
+
+// Start starts a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns the trace ID of the span in ctx, or a random ID when there
+// is no sampled span to take one from.
+func TraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID().String()
+	}
+	return uuid.New().String()
+}
+
+// Annotations returns the annotations carrying the trace context of ctx, or
+// nil when ctx carries none.
+func Annotations(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+
+	traceParent := carrier.Get("traceparent")
+	if traceParent == "" {
+		return nil
+	}
+
+	return map[string]string{TraceParentAnnotationKey: traceParent}
+}
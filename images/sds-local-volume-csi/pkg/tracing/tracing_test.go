@@ -0,0 +1,97 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	prev := otel.GetTracerProvider()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	return recorder
+}
+
+func TestTraceIDWithoutSpan(t *testing.T) {
+	first := TraceID(context.Background())
+	second := TraceID(context.Background())
+
+	assert.NotEmpty(t, first)
+	assert.NotEqual(t, first, second)
+	assert.Nil(t, Annotations(context.Background()))
+}
+
+func TestSpansShareTheTraceID(t *testing.T) {
+	recorder := useRecorder(t)
+
+	ctx, parent := Start(context.Background(), "csi.v1.Controller/CreateVolume")
+	traceID := TraceID(ctx)
+
+	childCtx, child := Start(ctx, "WaitForLVMLogicalVolume")
+	End(child, errors.New("timed out"))
+	End(parent, nil)
+
+	assert.Equal(t, traceID, TraceID(childCtx))
+	assert.Equal(t, parent.SpanContext().TraceID().String(), traceID)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "WaitForLVMLogicalVolume", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+}
+
+func TestAnnotations(t *testing.T) {
+	useRecorder(t)
+
+	ctx, span := Start(context.Background(), "CreateLVMLogicalVolume")
+	defer span.End()
+
+	annotations := Annotations(ctx)
+	require.Contains(t, annotations, TraceParentAnnotationKey)
+
+	// version-traceid-spanid-flags
+	parts := strings.Split(annotations[TraceParentAnnotationKey], "-")
+	require.Len(t, parts, 4)
+	assert.Equal(t, span.SpanContext().TraceID().String(), parts[1])
+	assert.Equal(t, span.SpanContext().SpanID().String(), parts[2])
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(context.Background(), ExporterNone, "", "test")
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), "jaeger", "", "test")
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	utilexec "k8s.io/utils/exec"
+)
+
+// blkidNotFound is the exit status of blkid when no device or tag matches.
+const blkidNotFound = 2
+
+// ensureUniqueBtrfsFSID gives the btrfs filesystem on source an fsid of its
+// own when another device of the node has the same one, as a clone or a
+// restored snapshot of a volume does. The kernel tells btrfs devices apart by
+// their fsid and would mount the filesystem of the other device instead.
+// Unlike the nouuid of xfs, no mount option helps; btrfstune -m only rewrites
+// the superblocks. A source that holds no btrfs yet is left to mkfs.
+func (s *Store) ensureUniqueBtrfsFSID(source string) error {
+	tags, err := s.blkid("--probe", "--output", "export", source)
+	if err != nil {
+		return err
+	}
+
+	var fsType, fsid string
+	for _, line := range tags {
+		if v, ok := strings.CutPrefix(line, "TYPE="); ok {
+			fsType = v
+		}
+		if v, ok := strings.CutPrefix(line, "UUID="); ok {
+			fsid = v
+		}
+	}
+	if fsType != "btrfs" || fsid == "" {
+		return nil
+	}
+
+	// The cache of blkid may miss the devices activated since it was written.
+	devices, err := s.blkid("--cache-file", "/dev/null", "--output", "device", "--match-token", "UUID="+fsid)
+	if err != nil {
+		return err
+	}
+	if !hasOtherDevice(devices, source) {
+		return nil
+	}
+
+	s.Log.Named("ensureUniqueBtrfsFSID").Info("another device has the same btrfs fsid, changing it", "source", source, "fsid", fsid)
+	out, err := s.NodeStorage.Exec.Command("btrfstune", "-f", "-m", source).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("btrfstune failed: %w, output: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// blkid runs blkid with args and returns the lines it prints, none when
+// nothing matches.
+func (s *Store) blkid(args ...string) ([]string, error) {
+	out, err := s.NodeStorage.Exec.Command("blkid", args...).CombinedOutput()
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitStatus() == blkidNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("blkid failed: %w, output: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return strings.Fields(string(out)), nil
+}
+
+// hasOtherDevice reports whether devices holds a device other than
+// source, telling the paths of one device apart by where they lead.
+func hasOtherDevice(devices []string, source string) bool {
+	for _, device := range devices {
+		if device != source && resolvePath(device) != resolvePath(source) {
+			return true
+		}
+	}
+	return false
+}
+
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
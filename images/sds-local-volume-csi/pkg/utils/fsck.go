@@ -0,0 +1,222 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	utilexec "k8s.io/utils/exec"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+)
+
+// FsckResult is the outcome of a filesystem check.
+type FsckResult string
+
+const (
+	// FsckClean means the check found no problem.
+	FsckClean FsckResult = "clean"
+	// FsckRepaired means the check found problems and repaired them.
+	FsckRepaired FsckResult = "repaired"
+	// FsckCorrupted means the check found problems it did not repair.
+	FsckCorrupted FsckResult = "corrupted"
+	// FsckSkipped means the filesystem was not checked: an ext filesystem
+	// unmounted cleanly under the ForceOnDirty policy, or an xfs filesystem
+	// with a dirty log.
+	FsckSkipped FsckResult = "skipped"
+)
+
+// Exit codes of e2fsck, which it ORs together.
+const (
+	e2fsckCorrected   = 1
+	e2fsckReboot      = 2
+	e2fsckUncorrected = 4
+)
+
+// xfsRepairCorrupted is the exit code of xfs_repair -n finding corruption.
+const xfsRepairCorrupted = 1
+
+// DiskFormat returns the filesystem on device, or "" for a blank device.
+func (s *Store) DiskFormat(device string) (string, error) {
+	return s.NodeStorage.GetDiskFormat(device)
+}
+
+// CheckFS checks the fsType filesystem on device, which must not be mounted,
+// according to the fsck policy of its class, and returns the outcome with the
+// output of the check. An error means the check itself could not be run.
+//
+// Only e2fsck repairs anything: xfs_repair and btrfs check run read-only, as
+// the repair of these filesystems may lose data and is left to an
+// administrator.
+func (s *Store) CheckFS(device, fsType, policy string) (FsckResult, string, error) {
+	switch fsType {
+	case internal.FSTypeExt4, internal.FSTypeExt3:
+		return s.checkExt(device, policy)
+	case internal.FSTypeXfs:
+		return s.checkXFS(device)
+	case internal.FSTypeBtrfs:
+		return s.checkBtrfs(device)
+	default:
+		return "", "", fmt.Errorf("no check for the %s filesystem", fsType)
+	}
+}
+
+func (s *Store) checkExt(device, policy string) (FsckResult, string, error) {
+	args := []string{"-p", device}
+	if policy == internal.FsckPolicyForceOnDirty {
+		dirty, err := s.extDirty(device)
+		if err != nil {
+			return "", "", err
+		}
+		if !dirty {
+			return FsckSkipped, "the filesystem was unmounted cleanly", nil
+		}
+		args = []string{"-f", "-p", device}
+	}
+
+	out, err := s.NodeStorage.Exec.Command("e2fsck", args...).CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	code, err := exitStatus(err)
+	if err != nil {
+		return "", output, fmt.Errorf("e2fsck failed: %w, output: %s", err, output)
+	}
+
+	switch {
+	case code == 0:
+		return FsckClean, output, nil
+	case code&^(e2fsckCorrected|e2fsckReboot|e2fsckUncorrected) != 0:
+		return "", output, fmt.Errorf("e2fsck exited with status %d, output: %s", code, output)
+	case code&e2fsckUncorrected != 0:
+		return FsckCorrupted, output, nil
+	default:
+		return FsckRepaired, output, nil
+	}
+}
+
+// extDirty tells whether the ext filesystem on device was not unmounted
+// cleanly: its state is not clean, or its journal has not been replayed.
+func (s *Store) extDirty(device string) (bool, error) {
+	out, err := s.NodeStorage.Exec.Command("dumpe2fs", "-h", device).CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("dumpe2fs failed: %w, output: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	var state string
+	var features []string
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Filesystem state":
+			state = strings.TrimSpace(value)
+		case "Filesystem features":
+			features = strings.Fields(value)
+		}
+	}
+	if state == "" {
+		return false, fmt.Errorf("no filesystem state in the dumpe2fs output: %s", strings.TrimSpace(string(out)))
+	}
+
+	return state != "clean" || slices.Contains(features, "needs_recovery"), nil
+}
+
+// checkXFS runs xfs_repair -n on an xfs filesystem with a clean log. With -n,
+// xfs_repair ignores the log and reports the metadata changes still in it as
+// corruption, and only the kernel replays the log, so a filesystem not
+// unmounted cleanly is left to the mount.
+func (s *Store) checkXFS(device string) (FsckResult, string, error) {
+	dirty, err := s.xfsDirtyLog(device)
+	if err != nil {
+		return "", "", err
+	}
+	if dirty {
+		return FsckSkipped, "the log is dirty and is replayed by the mount", nil
+	}
+
+	out, err := s.NodeStorage.Exec.Command("xfs_repair", "-n", device).CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	code, err := exitStatus(err)
+	if err != nil {
+		return "", output, fmt.Errorf("xfs_repair failed: %w, output: %s", err, output)
+	}
+
+	switch code {
+	case 0:
+		return FsckClean, output, nil
+	case xfsRepairCorrupted:
+		return FsckCorrupted, output, nil
+	default:
+		return "", output, fmt.Errorf("xfs_repair exited with status %d, output: %s", code, output)
+	}
+}
+
+// xfsDirtyLog tells whether the log of the xfs filesystem on device holds
+// changes not written to the filesystem, from the state in the header
+// xfs_logprint -t prints, as in "log tail: 8 head: 8 state: <CLEAN>".
+func (s *Store) xfsDirtyLog(device string) (bool, error) {
+	out, err := s.NodeStorage.Exec.Command("xfs_logprint", "-t", device).CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("xfs_logprint failed: %w, output: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		_, state, ok := strings.Cut(line, "state:")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(state) {
+		case "<CLEAN>":
+			return false, nil
+		case "<DIRTY>":
+			return true, nil
+		}
+	}
+
+	return false, fmt.Errorf("no log state in the xfs_logprint output: %s", strings.TrimSpace(string(out)))
+}
+
+func (s *Store) checkBtrfs(device string) (FsckResult, string, error) {
+	out, err := s.NodeStorage.Exec.Command("btrfs", "check", "--readonly", device).CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	code, err := exitStatus(err)
+	if err != nil {
+		return "", output, fmt.Errorf("btrfs check failed: %w, output: %s", err, output)
+	}
+	if code != 0 {
+		return FsckCorrupted, output, nil
+	}
+
+	return FsckClean, output, nil
+}
+
+// exitStatus returns the exit status of a command that ran, and err if it did
+// not run at all.
+func exitStatus(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), nil
+	}
+	return 0, err
+}
@@ -23,7 +23,6 @@ import (
 	"slices"
 	"time"
 
-	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"gopkg.in/yaml.v2"
@@ -31,6 +30,7 @@ import (
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	slv "github.com/deckhouse/sds-local-volume/api/v1alpha1"
@@ -48,8 +48,53 @@ const (
 	KubernetesAPIRequestLimit   = 3
 	KubernetesAPIRequestTimeout = 1
 	SDSLocalVolumeCSIFinalizer  = "storage.deckhouse.io/sds-local-volume-csi"
+
+	// Keys of the labels and annotations naming the workload an LVMLogicalVolume
+	// was provisioned for. images/controller/pkg/controller holds another copy.
+	PVCNameMetadataKey      = "storage.deckhouse.io/pvc-name"
+	PVCNamespaceMetadataKey = "storage.deckhouse.io/pvc-namespace"
+	PVNameMetadataKey       = "storage.deckhouse.io/pv-name"
 )
 
+// LLVMetadataFromParameters returns the labels and annotations recording the
+// PVC and PV a volume is provisioned for, as passed by the external-provisioner
+// in the CreateVolume parameters.
+//
+// The annotations always carry the full values. The labels, which are what
+// `kubectl get llv -l` selects on, are set only for the values that are valid
+// label values: a PVC name may be up to 253 characters long, a label value 63.
+//
+// The LVMLogicalVolume spec has no field for LVM tags, so the logical volume on
+// the node does not carry this information; the resource is the only place
+// holding it. The node plugin sets only the tags a class asks for with
+// LVTagsKey.
+func LLVMetadataFromParameters(params map[string]string) (labels, annotations map[string]string) {
+	for paramKey, metadataKey := range map[string]string{
+		internal.PVCNameKey:      PVCNameMetadataKey,
+		internal.PVCNamespaceKey: PVCNamespaceMetadataKey,
+		internal.PVNameKey:       PVNameMetadataKey,
+	} {
+		value := params[paramKey]
+		if value == "" {
+			continue
+		}
+
+		if annotations == nil {
+			annotations = make(map[string]string, 3)
+		}
+		annotations[metadataKey] = value
+
+		if len(validation.IsValidLabelValue(value)) == 0 {
+			if labels == nil {
+				labels = make(map[string]string, 3)
+			}
+			labels[metadataKey] = value
+		}
+	}
+
+	return labels, annotations
+}
+
 func CreateLVMLogicalVolumeSnapshot(
 	ctx context.Context,
 	kc client.Client,
@@ -241,11 +286,13 @@ func GetLSCBeforeLLVDelete(ctx context.Context, cl client.Client, log logger.Log
 	return &localStorageClass, nil
 }
 
-func CreateLVMLogicalVolume(ctx context.Context, kc client.Client, log logger.Logger, name string, lvmLogicalVolumeSpec snc.LVMLogicalVolumeSpec) (*snc.LVMLogicalVolume, error) {
+func CreateLVMLogicalVolume(ctx context.Context, kc client.Client, log logger.Logger, name string, labels, annotations map[string]string, lvmLogicalVolumeSpec snc.LVMLogicalVolumeSpec) (*snc.LVMLogicalVolume, error) {
 	var err error
 	llv := &snc.LVMLogicalVolume{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            name,
+			Labels:          labels,
+			Annotations:     annotations,
 			OwnerReferences: []metav1.OwnerReference{},
 			Finalizers:      []string{SDSLocalVolumeCSIFinalizer},
 		},
@@ -619,12 +666,12 @@ func removeLLVFinalizerIfExist(ctx context.Context, kc client.Client, log logger
 	return false, fmt.Errorf("after %d attempts of removing finalizer %s from LVMLogicalVolume %s, last error: %w", KubernetesAPIRequestLimit, finalizer, llv.Name, nil)
 }
 
-func IsContiguous(request *csi.CreateVolumeRequest, lvmType string) bool {
+func IsContiguous(params map[string]string, lvmType string) bool {
 	if lvmType == internal.LVMTypeThin {
 		return false
 	}
 
-	val, exist := request.Parameters[internal.LVMVThickContiguousParamKey]
+	val, exist := params[internal.LVMVThickContiguousParamKey]
 	if exist {
 		return val == "true"
 	}
@@ -0,0 +1,64 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/internal"
+)
+
+func TestLLVMetadataFromParameters(t *testing.T) {
+	t.Run("records_the_pvc_and_the_pv", func(t *testing.T) {
+		labels, annotations := LLVMetadataFromParameters(map[string]string{
+			internal.PVCNameKey:      "data-postgres-0",
+			internal.PVCNamespaceKey: "db",
+			internal.PVNameKey:       "pvc-3f1c",
+			internal.LvmTypeKey:      internal.LVMTypeThick,
+		})
+
+		want := map[string]string{
+			PVCNameMetadataKey:      "data-postgres-0",
+			PVCNamespaceMetadataKey: "db",
+			PVNameMetadataKey:       "pvc-3f1c",
+		}
+		assert.Equal(t, want, labels)
+		assert.Equal(t, want, annotations)
+	})
+
+	t.Run("keeps_a_long_pvc_name_out_of_the_labels", func(t *testing.T) {
+		long := strings.Repeat("a", 100)
+
+		labels, annotations := LLVMetadataFromParameters(map[string]string{
+			internal.PVCNameKey:      long,
+			internal.PVCNamespaceKey: "db",
+		})
+
+		assert.Equal(t, map[string]string{PVCNamespaceMetadataKey: "db"}, labels)
+		assert.Equal(t, long, annotations[PVCNameMetadataKey])
+	})
+
+	t.Run("returns_nil_without_extra_create_metadata", func(t *testing.T) {
+		labels, annotations := LLVMetadataFromParameters(map[string]string{internal.LvmTypeKey: internal.LVMTypeThin})
+
+		assert.Nil(t, labels)
+		assert.Nil(t, annotations)
+	})
+}
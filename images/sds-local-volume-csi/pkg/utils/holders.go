@@ -0,0 +1,220 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// What a process holds a mount by.
+const (
+	HeldByOpenFile   = "open file"
+	HeldByWorkingDir = "working directory"
+	HeldByRootDir    = "root directory"
+	// HeldByMount is a process of another mount namespace, such as a
+	// container, that has the filesystem mounted.
+	HeldByMount = "mount"
+)
+
+// containerIDRe matches the ID of a container in /proc/<pid>/cgroup, as in
+// ".../cri-containerd-<id>.scope" or ".../docker/<id>".
+var containerIDRe = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// MountHolder is a process that keeps a mount busy.
+type MountHolder struct {
+	PID     int
+	Command string
+	// Container is the ID of the container of the process, empty for a
+	// process of the host.
+	Container string
+	By        string
+}
+
+func (h MountHolder) String() string {
+	s := fmt.Sprintf("pid %d (%s) by %s", h.PID, h.Command, h.By)
+	if h.Container != "" {
+		s += ", container " + h.Container[:12]
+	}
+	return s
+}
+
+// DescribeHolders lists the holders of a mount for an error or an event.
+func DescribeHolders(holders []MountHolder) string {
+	if len(holders) == 0 {
+		return "no process found"
+	}
+	descriptions := make([]string, 0, len(holders))
+	for _, h := range holders {
+		descriptions = append(descriptions, h.String())
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+// MountHolders returns the processes that keep the mount at target busy, read
+// from procDir, the /proc of the host: the ones with a file, the working or
+// the root directory in it, and one process of every other mount namespace
+// that has its filesystem mounted. The mount namespaces of the plugin and of
+// the host, where the kubelet mounted the filesystem, are not among those.
+//
+// The processes that exit or that cannot be read meanwhile are skipped.
+func (s *Store) MountHolders(procDir, target string) ([]MountHolder, error) {
+	target = filepath.Clean(target)
+
+	self, err := os.Readlink(filepath.Join(procDir, "self"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the process of the plugin in %s: %w", procDir, err)
+	}
+	selfNS, _ := os.Readlink(filepath.Join(procDir, self, "ns", "mnt"))
+	hostNS, _ := os.Readlink(filepath.Join(procDir, "1", "ns", "mnt"))
+	device, err := mountDevice(filepath.Join(procDir, self, "mountinfo"), target)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the processes in %s: %w", procDir, err)
+	}
+
+	var holders []MountHolder
+	namespaces := map[string]bool{selfNS: true, hostNS: true}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join(procDir, entry.Name())
+
+		by := heldBy(dir, target)
+		if by == "" && device != "" {
+			ns, err := os.Readlink(filepath.Join(dir, "ns", "mnt"))
+			if err == nil && !namespaces[ns] {
+				namespaces[ns] = true
+				if hasDevice(filepath.Join(dir, "mountinfo"), device) {
+					by = HeldByMount
+				}
+			}
+		}
+		if by == "" {
+			continue
+		}
+
+		holders = append(holders, MountHolder{
+			PID:       pid,
+			Command:   readTrimmed(filepath.Join(dir, "comm")),
+			Container: containerIDRe.FindString(readTrimmed(filepath.Join(dir, "cgroup"))),
+			By:        by,
+		})
+	}
+
+	return holders, nil
+}
+
+// heldBy tells what the process with the /proc directory dir holds a path
+// under target by, or "".
+func heldBy(dir, target string) string {
+	under := func(path string) bool {
+		return path == target || strings.HasPrefix(path, target+"/")
+	}
+
+	if path, err := os.Readlink(filepath.Join(dir, "cwd")); err == nil && under(path) {
+		return HeldByWorkingDir
+	}
+	if path, err := os.Readlink(filepath.Join(dir, "root")); err == nil && under(path) {
+		return HeldByRootDir
+	}
+
+	fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil {
+		return ""
+	}
+	for _, fd := range fds {
+		if path, err := os.Readlink(filepath.Join(dir, "fd", fd.Name())); err == nil && under(path) {
+			return HeldByOpenFile
+		}
+	}
+
+	return ""
+}
+
+// mountDevice returns the major:minor of the filesystem mounted at target
+// according to the mountinfo file, or "" if nothing is mounted there.
+func mountDevice(mountinfo, target string) (string, error) {
+	f, err := os.Open(mountinfo)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %w", mountinfo, err)
+	}
+	defer f.Close()
+
+	var device string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt/parent rw,noatime master:1 - ext3 /dev/root rw
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		// The last mount at a path is the one in effect.
+		if fields[4] == target {
+			device = fields[2]
+		}
+	}
+
+	return device, scanner.Err()
+}
+
+// hasDevice tells whether the mountinfo file has a mount of the filesystem
+// with the major:minor device.
+func hasDevice(mountinfo, device string) bool {
+	data, err := os.ReadFile(mountinfo)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 3 && fields[2] == device {
+			return true
+		}
+	}
+	return false
+}
+
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// LazyUnmount detaches the mount at target at once and leaves the filesystem
+// to be unmounted once its holders let go of it, then removes target.
+func (s *Store) LazyUnmount(target string) error {
+	out, err := s.NodeStorage.Exec.Command("umount", "--lazy", target).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("umount --lazy failed: %w, output: %s", err, strings.TrimSpace(string(out)))
+	}
+	if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove %s: %w", target, err)
+	}
+	return nil
+}
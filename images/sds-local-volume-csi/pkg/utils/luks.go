@@ -0,0 +1,115 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// LUKSFormat is what blkid reports for a LUKS device.
+const LUKSFormat = "crypto_LUKS"
+
+// LUKSMapperPath returns the device an encrypted volume is opened at.
+func LUKSMapperPath(volumeID string) string {
+	return "/dev/mapper/" + luksMapperName(volumeID)
+}
+
+func luksMapperName(volumeID string) string {
+	return "luks-" + volumeID
+}
+
+// OpenLUKS opens the LUKS device devPath of a volume and returns the path of
+// the plain device. A blank devPath, which is what a new volume is, is
+// formatted as LUKS2 first. A volume that is open already is left as it is.
+//
+// A devPath holding anything other than LUKS is refused rather than formatted:
+// it is a volume with data on it, restored or cloned from a plain one.
+func (s *Store) OpenLUKS(devPath, volumeID string, passphrase []byte) (string, error) {
+	log := s.Log.Named("OpenLUKS").With("devicePath", devPath)
+
+	mapperPath := LUKSMapperPath(volumeID)
+	open, err := s.PathExists(mapperPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to check whether %s exists: %w", mapperPath, err)
+	}
+	if open {
+		log.Debug("the LUKS device is open already", "mapperPath", mapperPath)
+		return mapperPath, nil
+	}
+
+	format, err := s.NodeStorage.GetDiskFormat(devPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to detect the format of %s: %w", devPath, err)
+	}
+
+	switch format {
+	case LUKSFormat:
+	case "":
+		log.Info("formatting the device as LUKS2")
+		if err := s.cryptsetup(passphrase, "luksFormat", "--type", "luks2", "--batch-mode", "--key-file", "-", devPath); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("the device %s holds %s rather than LUKS, refusing to format it", devPath, format)
+	}
+
+	log.Info("opening the LUKS device", "mapperPath", mapperPath)
+	if err := s.cryptsetup(passphrase, "luksOpen", "--key-file", "-", devPath, luksMapperName(volumeID)); err != nil {
+		return "", err
+	}
+
+	return mapperPath, nil
+}
+
+// CloseLUKS closes the LUKS device of a volume if it is open.
+func (s *Store) CloseLUKS(volumeID string) error {
+	open, err := s.PathExists(LUKSMapperPath(volumeID))
+	if err != nil {
+		return fmt.Errorf("unable to check whether %s exists: %w", LUKSMapperPath(volumeID), err)
+	}
+	if !open {
+		return nil
+	}
+
+	s.Log.Named("CloseLUKS").Info("closing the LUKS device", "mapperPath", LUKSMapperPath(volumeID))
+	return s.cryptsetup(nil, "luksClose", luksMapperName(volumeID))
+}
+
+// ResizeLUKS grows the open LUKS device of a volume to the size of the LV
+// underneath. LUKS2 may ask for the passphrase to do so.
+func (s *Store) ResizeLUKS(volumeID string, passphrase []byte) error {
+	s.Log.Named("ResizeLUKS").Info("resizing the LUKS device", "mapperPath", LUKSMapperPath(volumeID))
+	return s.cryptsetup(passphrase, "resize", "--key-file", "-", luksMapperName(volumeID))
+}
+
+// cryptsetup runs cryptsetup with args, passing the passphrase on stdin so
+// that it shows up neither in the process list nor on disk.
+func (s *Store) cryptsetup(passphrase []byte, args ...string) error {
+	cmd := s.NodeStorage.Exec.Command("cryptsetup", args...)
+	if passphrase != nil {
+		cmd.SetStdin(bytes.NewReader(passphrase))
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("cryptsetup %s failed: %w, output: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// reservedLVTagPrefix starts the LV tags sds-node-configurator sets itself.
+	// They can neither be requested nor removed.
+	reservedLVTagPrefix = "storage.deckhouse.io"
+	// maxLVTagLength is the longest tag LVM accepts.
+	maxLVTagLength = 1024
+)
+
+// ParseLVTags parses a comma-separated list of LV tags and checks each one
+// against the rules of lvm(8) "VALID NAMES". An empty list is valid.
+func ParseLVTags(value string) ([]string, error) {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+
+		switch {
+		case len(tag) > maxLVTagLength:
+			return nil, fmt.Errorf("the tag %q is longer than %d characters", tag, maxLVTagLength)
+		case tag[0] == '-':
+			return nil, fmt.Errorf("the tag %q must not start with '-'", tag)
+		case strings.HasPrefix(tag, reservedLVTagPrefix):
+			return nil, fmt.Errorf("the tag %q must not start with %q, sds-node-configurator reserves it", tag, reservedLVTagPrefix)
+		}
+
+		for _, r := range tag {
+			if !isValidLVTagRune(r) {
+				return nil, fmt.Errorf("the tag %q contains %q, only the characters a-z, A-Z, 0-9 and '_+.-/=!:&#' are allowed", tag, r)
+			}
+		}
+
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags, nil
+}
+
+func isValidLVTagRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("_+.-/=!:&#", r)
+}
+
+// SyncLVTags makes tags the tags of the LV at lvPath, leaving the reserved ones
+// alone. Nothing runs if the LV has them already; the change is made with the
+// LVM of the host, see hostLVM.
+func (s *Store) SyncLVTags(lvPath string, tags []string) error {
+	out, err := s.NodeStorage.Exec.Command("lvs", "--readonly", "--noheadings", "--options", "lv_tags", lvPath).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to list the tags of %s: %w, output: %s", lvPath, err, strings.TrimSpace(string(out)))
+	}
+
+	var current []string
+	for _, tag := range strings.Split(strings.TrimSpace(string(out)), ",") {
+		if tag != "" && !strings.HasPrefix(tag, reservedLVTagPrefix) {
+			current = append(current, tag)
+		}
+	}
+
+	var args []string
+	for _, tag := range tags {
+		if !slices.Contains(current, tag) {
+			args = append(args, "--addtag", tag)
+		}
+	}
+	for _, tag := range current {
+		if !slices.Contains(tags, tag) {
+			args = append(args, "--deltag", tag)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	s.Log.Named("SyncLVTags").Info("changing the tags of the LV", "lvPath", lvPath, "args", args)
+	out, err = s.hostLVM("lvchange", append(args, lvPath)...)
+	if err != nil {
+		return fmt.Errorf("unable to change the tags of %s: %w, output: %s", lvPath, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
@@ -17,14 +17,34 @@ limitations under the License.
 package utils
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	mountutils "k8s.io/mount-utils"
+	utilexec "k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
 
 	"github.com/deckhouse/sds-local-volume/images/sds-local-volume-csi/pkg/logger"
 )
 
+// failingMounter fails the mounts at target.
+type failingMounter struct {
+	*mountutils.FakeMounter
+	target string
+}
+
+func (m failingMounter) Mount(source, target, fsType string, options []string) error {
+	if target == m.target {
+		return errors.New("wrong fs type")
+	}
+	return m.FakeMounter.Mount(source, target, fsType, options)
+}
+
 func TestNodeStoreManager(t *testing.T) {
 	t.Run("toMapperPath", func(t *testing.T) {
 		t.Run("does_not_have_prefix_returns_empty", func(t *testing.T) {
@@ -109,4 +129,489 @@ func TestNodeStoreManager(t *testing.T) {
 			assert.ErrorContains(t, err, "[checkMount] mount point \"some-target\" not found in mount info")
 		})
 	})
+
+	t.Run("LVDevicePath", func(t *testing.T) {
+		storeWithLVSOutput := func(out string, err error) (*Store, *[]string) {
+			var args []string
+			fakeExec := &testingexec.FakeExec{
+				CommandScript: []testingexec.FakeCommandAction{
+					func(cmd string, a ...string) utilexec.Cmd {
+						args = append([]string{cmd}, a...)
+						return &testingexec.FakeCmd{
+							CombinedOutputScript: []testingexec.FakeAction{
+								func() ([]byte, []byte, error) { return []byte(out), nil, err },
+							},
+						}
+					},
+				},
+			}
+			return &Store{
+				Log:         logger.NewNop(),
+				NodeStorage: mountutils.SafeFormatAndMount{Exec: fakeExec},
+			}, &args
+		}
+
+		t.Run("follows_a_renamed_vg", func(t *testing.T) {
+			store, args := storeWithLVSOutput("  WARNING: some devices are missing\n  /dev/vg-renamed/pvc-1\n", nil)
+
+			path, err := store.LVDevicePath("", "Y3cP1x-uuid", "pvc-1")
+			assert.NoError(t, err)
+			assert.Equal(t, "/dev/vg-renamed/pvc-1", path)
+			assert.Contains(t, *args, `vg_uuid="Y3cP1x-uuid" && lv_name="pvc-1"`)
+		})
+
+		t.Run("selects_on_the_lv_uuid", func(t *testing.T) {
+			store, args := storeWithLVSOutput("  /dev/vg-renamed/pvc-renamed\n", nil)
+
+			path, err := store.LVDevicePath("fmd3Lc-0Ezt-Bvbq-LGmJ-7X3T-g0XW-9pDMQz", "Y3cP1x-uuid", "pvc-1")
+			assert.NoError(t, err)
+			assert.Equal(t, "/dev/vg-renamed/pvc-renamed", path)
+			assert.Contains(t, *args, `lv_uuid="fmd3Lc-0Ezt-Bvbq-LGmJ-7X3T-g0XW-9pDMQz"`)
+		})
+
+		t.Run("no_match_returns_not_found", func(t *testing.T) {
+			store, _ := storeWithLVSOutput("", nil)
+
+			_, err := store.LVDevicePath("", "Y3cP1x-uuid", "pvc-1")
+			assert.ErrorIs(t, err, ErrLVNotFound)
+		})
+
+		t.Run("lvs_failure_returns_error", func(t *testing.T) {
+			store, _ := storeWithLVSOutput("  Volume group not found", errors.New("exit status 5"))
+
+			_, err := store.LVDevicePath("", "Y3cP1x-uuid", "pvc-1")
+			assert.ErrorContains(t, err, "Volume group not found")
+			assert.NotErrorIs(t, err, ErrLVNotFound)
+		})
+
+		t.Run("lv_uuid_skips_warnings", func(t *testing.T) {
+			store, args := storeWithLVSOutput("  WARNING: some devices are missing\n  fmd3Lc-0Ezt-Bvbq-LGmJ-7X3T-g0XW-9pDMQz\n", nil)
+
+			uuid, err := store.LVUUID("/dev/vg/pvc-1")
+			assert.NoError(t, err)
+			assert.Equal(t, "fmd3Lc-0Ezt-Bvbq-LGmJ-7X3T-g0XW-9pDMQz", uuid)
+			assert.Equal(t, "/dev/vg/pvc-1", (*args)[len(*args)-1])
+		})
+	})
+
+	t.Run("ParseLVTags", func(t *testing.T) {
+		tags, err := ParseLVTags("backup=daily, tier:gold,,backup=daily")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"backup=daily", "tier:gold"}, tags)
+
+		for _, value := range []string{"-leading", "with space", "storage.deckhouse.io/enabled=true"} {
+			_, err := ParseLVTags(value)
+			assert.Error(t, err, value)
+		}
+	})
+
+	t.Run("SyncLVTags", func(t *testing.T) {
+		var commands [][]string
+		fakeExec := &testingexec.FakeExec{}
+		for _, out := range []string{"  storage.deckhouse.io/enabled=true,tier:silver,backup=daily\n", ""} {
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, a ...string) utilexec.Cmd {
+				commands = append(commands, append([]string{cmd}, a...))
+				return &testingexec.FakeCmd{
+					CombinedOutputScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) { return []byte(out), nil, nil },
+					},
+				}
+			})
+		}
+		store := &Store{Log: logger.NewNop(), NodeStorage: mountutils.SafeFormatAndMount{Exec: fakeExec}}
+
+		assert.NoError(t, store.SyncLVTags("/dev/vg/pvc-1", []string{"backup=daily", "tier:gold"}))
+		assert.Len(t, commands, 2)
+		assert.Equal(t, []string{"nsenter", "--mount=/host/proc/1/ns/mnt", "--", "/opt/deckhouse/sds/bin/lvm.static", "lvchange", "--addtag", "tier:gold", "--deltag", "tier:silver", "/dev/vg/pvc-1"}, commands[1])
+	})
+
+	t.Run("ActivateReadOnly", func(t *testing.T) {
+		var commands [][]string
+		fakeExec := &testingexec.FakeExec{}
+		for range 4 {
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, a ...string) utilexec.Cmd {
+				commands = append(commands, append([]string{cmd}, a...))
+				return &testingexec.FakeCmd{
+					CombinedOutputScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) { return nil, nil, nil },
+					},
+				}
+			})
+		}
+		store := &Store{Log: logger.NewNop(), NodeStorage: mountutils.SafeFormatAndMount{Exec: fakeExec}}
+
+		assert.NoError(t, store.ActivateReadOnly("/dev/vg/snap-1"))
+		assert.Equal(t, [][]string{
+			{"nsenter", "--mount=/host/proc/1/ns/mnt", "--", "/opt/deckhouse/sds/bin/lvm.static", "lvchange", "--activate", "y", "--ignoreactivationskip", "/dev/vg/snap-1"},
+			{"blockdev", "--setro", "/dev/vg/snap-1"},
+		}, commands)
+
+		commands = nil
+		assert.NoError(t, store.DeactivateReadOnly("/dev/vg/snap-1"))
+		assert.Equal(t, [][]string{
+			{"blockdev", "--setrw", "/dev/vg/snap-1"},
+			{"nsenter", "--mount=/host/proc/1/ns/mnt", "--", "/opt/deckhouse/sds/bin/lvm.static", "lvchange", "--activate", "n", "/dev/vg/snap-1"},
+		}, commands)
+	})
+
+	t.Run("ThinPoolOutOfDataSpace", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			outputs []string
+			full    bool
+			pool    string
+		}{
+			"thick":           {outputs: []string{"  |vg\n"}},
+			"thin with space": {outputs: []string{"  pool|vg\n", "  twi-aotz--|42.10\n"}, pool: "vg/pool"},
+			"out of space":    {outputs: []string{"  pool|vg\n", "  twi-aotzD-|100.00\n"}, full: true, pool: "vg/pool"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				fakeExec := &testingexec.FakeExec{}
+				for _, out := range tc.outputs {
+					fakeExec.CommandScript = append(fakeExec.CommandScript, func(string, ...string) utilexec.Cmd {
+						return &testingexec.FakeCmd{
+							CombinedOutputScript: []testingexec.FakeAction{
+								func() ([]byte, []byte, error) { return []byte(out), nil, nil },
+							},
+						}
+					})
+				}
+				store := &Store{Log: logger.NewNop(), NodeStorage: mountutils.SafeFormatAndMount{Exec: fakeExec}}
+
+				full, pool, err := store.ThinPoolOutOfDataSpace("/dev/vg/pvc-1")
+				assert.NoError(t, err)
+				assert.Equal(t, tc.full, full)
+				assert.Equal(t, tc.pool, pool)
+			})
+		}
+	})
+
+	t.Run("ThinVolumeUsage", func(t *testing.T) {
+		fakeExec := &testingexec.FakeExec{}
+		for _, out := range []string{"  pool|vg|10737418240|25.00\n", "  107374182400|90.00\n"} {
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(string, ...string) utilexec.Cmd {
+				return &testingexec.FakeCmd{
+					CombinedOutputScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) { return []byte(out), nil, nil },
+					},
+				}
+			})
+		}
+		store := &Store{Log: logger.NewNop(), NodeStorage: mountutils.SafeFormatAndMount{Exec: fakeExec}}
+
+		used, poolFree, thin, err := store.ThinVolumeUsage("/dev/vg/pvc-1")
+		assert.NoError(t, err)
+		assert.True(t, thin)
+		assert.Equal(t, uint64(2684354560), used)
+		assert.Equal(t, uint64(10737418240), poolFree)
+	})
+
+	t.Run("ensureUniqueBtrfsFSID", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			outputs []string
+			tuned   bool
+		}{
+			"duplicate_fsid": {outputs: []string{"DEVNAME=/dev/vg/clone\nUUID=1234\nTYPE=btrfs\n", "/dev/vg/source\n/dev/vg/clone\n"}, tuned: true},
+			"unique_fsid":    {outputs: []string{"DEVNAME=/dev/vg/clone\nUUID=1234\nTYPE=btrfs\n", "/dev/vg/clone\n"}},
+			"other_fs":       {outputs: []string{"DEVNAME=/dev/vg/clone\nUUID=1234\nTYPE=ext4\n"}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				fakeExec := &testingexec.FakeExec{}
+				for _, out := range tc.outputs {
+					fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, _ ...string) utilexec.Cmd {
+						assert.Equal(t, "blkid", cmd)
+						return &testingexec.FakeCmd{
+							CombinedOutputScript: []testingexec.FakeAction{
+								func() ([]byte, []byte, error) { return []byte(out), nil, nil },
+							},
+						}
+					})
+				}
+				tuned := false
+				fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) utilexec.Cmd {
+					assert.Equal(t, "btrfstune", cmd)
+					assert.Equal(t, []string{"-f", "-m", "/dev/vg/clone"}, args)
+					tuned = true
+					return &testingexec.FakeCmd{
+						CombinedOutputScript: []testingexec.FakeAction{
+							func() ([]byte, []byte, error) { return nil, nil, nil },
+						},
+					}
+				})
+				store := &Store{Log: logger.NewNop(), NodeStorage: mountutils.SafeFormatAndMount{Exec: fakeExec}}
+
+				assert.NoError(t, store.ensureUniqueBtrfsFSID("/dev/vg/clone"))
+				assert.Equal(t, tc.tuned, tuned)
+			})
+		}
+
+		t.Run("blank_device", func(t *testing.T) {
+			fakeExec := &testingexec.FakeExec{}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(string, ...string) utilexec.Cmd {
+				return &testingexec.FakeCmd{
+					CombinedOutputScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) { return nil, nil, testingexec.FakeExitError{Status: 2} },
+					},
+				}
+			})
+			store := &Store{Log: logger.NewNop(), NodeStorage: mountutils.SafeFormatAndMount{Exec: fakeExec}}
+
+			assert.NoError(t, store.ensureUniqueBtrfsFSID("/dev/vg/clone"))
+			assert.Equal(t, 1, fakeExec.CommandCalls)
+		})
+	})
+
+	t.Run("ProbeXFSFeatures", func(t *testing.T) {
+		dir := t.TempDir()
+		fakeExec := &testingexec.FakeExec{}
+		var formatted [][]string
+		fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) utilexec.Cmd {
+			assert.Equal(t, "mkfs.xfs", cmd)
+			var err error
+			// This mkfs.xfs predates nrext64.
+			if slices.Contains(args, "nrext64=1") {
+				err = testingexec.FakeExitError{Status: 1}
+			}
+			if !slices.Contains(args, "-N") {
+				formatted = append(formatted, args)
+			}
+			return &testingexec.FakeCmd{
+				CombinedOutputScript: []testingexec.FakeAction{
+					func() ([]byte, []byte, error) { return nil, nil, err },
+				},
+			}
+		})
+		for range 7 {
+			fakeExec.CommandScript = append(fakeExec.CommandScript, fakeExec.CommandScript[0])
+		}
+		// This kernel mounts no reflink.
+		mounter := failingMounter{FakeMounter: mountutils.NewFakeMounter(nil), target: dir + "/probe/mnt-reflink"}
+		store := &Store{Log: logger.NewNop(), NodeStorage: mountutils.SafeFormatAndMount{Interface: mounter, Exec: fakeExec}}
+
+		support, err := store.ProbeXFSFeatures(dir + "/probe")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]XFSSupport{
+			"bigtime":    {Mkfs: true, Kernel: true},
+			"inobtcount": {Mkfs: true, Kernel: true},
+			"reflink":    {Mkfs: true},
+			"nrext64":    {},
+		}, support)
+		assert.Equal(t, []string{"-f", "-m", "bigtime=0,inobtcount=0,reflink=0", dir + "/probe/xfs.img"}, formatted[0])
+		assert.Equal(t, []string{"-f", "-m", "bigtime=0,inobtcount=0,reflink=1", dir + "/probe/xfs.img"}, formatted[3])
+		assert.NoDirExists(t, dir+"/probe")
+	})
+
+	t.Run("Trim", func(t *testing.T) {
+		fakeExec := &testingexec.FakeExec{}
+		fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) utilexec.Cmd {
+			assert.Equal(t, "fstrim", cmd)
+			assert.Equal(t, []string{"--verbose", "/staging/pvc-1"}, args)
+			return &testingexec.FakeCmd{
+				CombinedOutputScript: []testingexec.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("/staging/pvc-1: 1 GiB (1073741824 bytes) trimmed\n"), nil, nil
+					},
+				},
+			}
+		})
+		store := &Store{Log: logger.NewNop(), NodeStorage: mountutils.SafeFormatAndMount{Exec: fakeExec}}
+
+		trimmed, err := store.Trim(context.Background(), "/staging/pvc-1")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1073741824), trimmed)
+	})
+
+	t.Run("ResizeFSOffline", func(t *testing.T) {
+		dir := t.TempDir()
+		fakeExec := &testingexec.FakeExec{}
+		var commands []string
+		for range 2 {
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) utilexec.Cmd {
+				commands = append(commands, cmd)
+				return &testingexec.FakeCmd{
+					CombinedOutputScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) {
+							if cmd == "blkid" {
+								return []byte("DEVNAME=/dev/vg/pvc-1\nTYPE=ext4\n"), nil, nil
+							}
+							return nil, nil, nil
+						},
+					},
+				}
+			})
+		}
+		mounter := &optionsMounter{FakeMounter: mountutils.NewFakeMounter(nil)}
+		store := &Store{Log: logger.NewNop(), NodeStorage: mountutils.SafeFormatAndMount{Interface: mounter, Exec: fakeExec}}
+
+		// The fake mounter mounts nothing: the size is that of the filesystem
+		// of the temporary directory.
+		size, err := store.ResizeFSOffline("/dev/vg/pvc-1", "ext4", dir+"/resize", []string{"noatime"})
+		assert.NoError(t, err)
+		assert.Positive(t, size)
+		assert.Equal(t, []string{"blkid", "resize2fs"}, commands)
+		log := mounter.GetLog()
+		if assert.Len(t, log, 2) {
+			assert.Equal(t, mountutils.FakeActionMount, log[0].Action)
+			assert.Equal(t, "/dev/vg/pvc-1", log[0].Source)
+			assert.Equal(t, "ext4", log[0].FSType)
+			assert.Equal(t, mountutils.FakeActionUnmount, log[1].Action)
+		}
+		assert.Equal(t, []string{"noatime"}, mounter.options)
+		entries, _ := os.ReadDir(dir + "/resize")
+		assert.Empty(t, entries)
+	})
+
+	t.Run("MountHolders", func(t *testing.T) {
+		proc := t.TempDir()
+		const target = "/var/lib/kubelet/pods/uid-1/volumes/kubernetes.io~csi/pvc-1/mount"
+		const container = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		process := func(pid, ns, comm, cgroup, mountinfo string, links map[string]string) {
+			dir := filepath.Join(proc, pid)
+			for _, sub := range []string{"ns", "fd"} {
+				assert.NoError(t, os.MkdirAll(filepath.Join(dir, sub), 0755))
+			}
+			assert.NoError(t, os.Symlink(ns, filepath.Join(dir, "ns", "mnt")))
+			for name, content := range map[string]string{"comm": comm + "\n", "cgroup": cgroup, "mountinfo": mountinfo} {
+				assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+			}
+			for link, to := range links {
+				assert.NoError(t, os.Symlink(to, filepath.Join(dir, link)))
+			}
+		}
+		hostMounts := "36 35 253:3 / " + target + " rw,relatime shared:1 - ext4 /dev/dm-3 rw\n"
+
+		// The init and the kubelet of the host, the plugin, a process of the
+		// plugin container with a file open in the mount, a container with
+		// the filesystem mounted and another process of it, and a process that
+		// has nothing to do with the mount.
+		process("1", "mnt:[0]", "systemd", "0::/init.scope\n", hostMounts, nil)
+		process("50", "mnt:[0]", "kubelet", "0::/system.slice/kubelet.service\n", hostMounts, nil)
+		process("100", "mnt:[1]", "sds-local-volume-csi", "0::/\n", hostMounts, nil)
+		process("200", "mnt:[1]", "postgres", "0::/kubepods/cri-containerd-"+container+".scope\n", hostMounts, map[string]string{"fd/3": target + "/base/1"})
+		process("300", "mnt:[2]", "sh", "0::/\n", "50 40 253:3 / /data rw - ext4 /dev/dm-3 rw\n", nil)
+		process("301", "mnt:[2]", "sleep", "0::/\n", "50 40 253:3 / /data rw - ext4 /dev/dm-3 rw\n", nil)
+		process("400", "mnt:[1]", "bash", "0::/\n", hostMounts, map[string]string{"cwd": "/root"})
+		assert.NoError(t, os.Symlink("100", filepath.Join(proc, "self")))
+
+		store := &Store{Log: logger.NewNop()}
+		holders, err := store.MountHolders(proc, target)
+		assert.NoError(t, err)
+		assert.Equal(t, []MountHolder{
+			{PID: 200, Command: "postgres", Container: container, By: HeldByOpenFile},
+			{PID: 300, Command: "sh", By: HeldByMount},
+		}, holders)
+		assert.Equal(t, "pid 200 (postgres) by open file, container 0123456789ab; pid 300 (sh) by mount", DescribeHolders(holders))
+	})
+
+	t.Run("CheckFS", func(t *testing.T) {
+		// run scripts the commands of a check, each exiting with its status.
+		run := func(t *testing.T, want [][]string, output []string, statuses []int) *Store {
+			fakeExec := &testingexec.FakeExec{}
+			for i := range want {
+				fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) utilexec.Cmd {
+					assert.Equal(t, want[i], append([]string{cmd}, args...))
+					return &testingexec.FakeCmd{
+						CombinedOutputScript: []testingexec.FakeAction{
+							func() ([]byte, []byte, error) {
+								if statuses[i] != 0 {
+									return []byte(output[i]), nil, testingexec.FakeExitError{Status: statuses[i]}
+								}
+								return []byte(output[i]), nil, nil
+							},
+						},
+					}
+				})
+			}
+			return &Store{Log: logger.NewNop(), NodeStorage: mountutils.SafeFormatAndMount{Exec: fakeExec}}
+		}
+		const dev = "/dev/vg/pvc-1"
+
+		for _, tc := range []struct {
+			name     string
+			fsType   string
+			policy   string
+			want     [][]string
+			output   []string
+			statuses []int
+			result   FsckResult
+			err      bool
+		}{
+			{name: "ext4_preen_clean", fsType: "ext4", policy: "Preen", want: [][]string{{"e2fsck", "-p", dev}}, output: []string{""}, statuses: []int{0}, result: FsckClean},
+			{name: "ext4_preen_repaired", fsType: "ext4", policy: "Preen", want: [][]string{{"e2fsck", "-p", dev}}, output: []string{""}, statuses: []int{1}, result: FsckRepaired},
+			{name: "ext4_preen_corrupted", fsType: "ext4", policy: "Preen", want: [][]string{{"e2fsck", "-p", dev}}, output: []string{"UNEXPECTED INCONSISTENCY"}, statuses: []int{4}, result: FsckCorrupted},
+			{name: "ext4_preen_operational_error", fsType: "ext4", policy: "Preen", want: [][]string{{"e2fsck", "-p", dev}}, output: []string{"cannot open"}, statuses: []int{8}, err: true},
+			{
+				name: "ext3_force_on_dirty_clean", fsType: "ext3", policy: "ForceOnDirty",
+				want:   [][]string{{"dumpe2fs", "-h", dev}},
+				output: []string{"Filesystem features:      has_journal ext_attr\nFilesystem state:         clean\n"}, statuses: []int{0},
+				result: FsckSkipped,
+			},
+			{
+				name: "ext4_force_on_dirty_needs_recovery", fsType: "ext4", policy: "ForceOnDirty",
+				want:   [][]string{{"dumpe2fs", "-h", dev}, {"e2fsck", "-f", "-p", dev}},
+				output: []string{"Filesystem features:      has_journal needs_recovery extent\nFilesystem state:         clean\n", ""}, statuses: []int{0, 1},
+				result: FsckRepaired,
+			},
+			{
+				name: "ext4_force_on_dirty_not_clean", fsType: "ext4", policy: "ForceOnDirty",
+				want:   [][]string{{"dumpe2fs", "-h", dev}, {"e2fsck", "-f", "-p", dev}},
+				output: []string{"Filesystem features:      has_journal extent\nFilesystem state:         not clean with errors\n", ""}, statuses: []int{0, 0},
+				result: FsckClean,
+			},
+			{
+				name: "xfs_clean", fsType: "xfs", policy: "Preen",
+				want:   [][]string{{"xfs_logprint", "-t", dev}, {"xfs_repair", "-n", dev}},
+				output: []string{xfsLogprint("<CLEAN>"), ""}, statuses: []int{0, 0},
+				result: FsckClean,
+			},
+			// xfs_repair -n would report the changes in the log as corruption.
+			{
+				name: "xfs_dirty_log", fsType: "xfs", policy: "ForceOnDirty",
+				want:   [][]string{{"xfs_logprint", "-t", dev}},
+				output: []string{xfsLogprint("<DIRTY>")}, statuses: []int{0},
+				result: FsckSkipped,
+			},
+			{
+				name: "xfs_corrupted", fsType: "xfs", policy: "Preen",
+				want:   [][]string{{"xfs_logprint", "-t", dev}, {"xfs_repair", "-n", dev}},
+				output: []string{xfsLogprint("<CLEAN>"), "would have junked entry"}, statuses: []int{0, 1},
+				result: FsckCorrupted,
+			},
+			{
+				name: "xfs_unknown_log_state", fsType: "xfs", policy: "Preen",
+				want:   [][]string{{"xfs_logprint", "-t", dev}},
+				output: []string{"xfs_logprint: unknown log operation type"}, statuses: []int{0},
+				err: true,
+			},
+			{name: "btrfs_corrupted", fsType: "btrfs", policy: "Preen", want: [][]string{{"btrfs", "check", "--readonly", dev}}, output: []string{"errors found in fs roots"}, statuses: []int{1}, result: FsckCorrupted},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				store := run(t, tc.want, tc.output, tc.statuses)
+
+				result, _, err := store.CheckFS(dev, tc.fsType, tc.policy)
+				if tc.err {
+					assert.Error(t, err)
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, tc.result, result)
+			})
+		}
+	})
+}
+
+// xfsLogprint is the header xfs_logprint -t prints for a log in state.
+func xfsLogprint(state string) string {
+	return "xfs_logprint:\n    data device: 0xfd01\n    log device: 0xfd01 daddr: 2097184 length: 20480\n\n    log tail: 8 head: 8 state: " + state + "\n"
+}
+
+// optionsMounter records the options of the last mount, which the log of the
+// fake mounter leaves out.
+type optionsMounter struct {
+	*mountutils.FakeMounter
+	options []string
+}
+
+func (m *optionsMounter) Mount(source, target, fsType string, options []string) error {
+	m.options = options
+	return m.FakeMounter.Mount(source, target, fsType, options)
 }
@@ -17,12 +17,16 @@ limitations under the License.
 package utils
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"regexp"
 	"slices"
 	"strings"
 
+	"golang.org/x/sys/unix"
 	mountutils "k8s.io/mount-utils"
 	utilexec "k8s.io/utils/exec"
 
@@ -31,17 +35,39 @@ import (
 )
 
 type NodeStoreManager interface {
-	NodeStageVolumeFS(source, target string, fsType string, mountOpts []string, formatOpts []string, lvmType, lvmThinPoolName string) error
+	NodeStageVolumeFS(source, target string, fsType string, mountOpts []string, formatOpts []string, lvmType, lvmThinPoolName string, checked bool) error
 	NodePublishVolumeBlock(source, target string, mountOpts []string) error
 	NodePublishVolumeFS(source, devPath, target, fsType string, mountOpts []string) error
 	Unstage(target string) error
 	Unpublish(target string) error
 	IsNotMountPoint(target string) (bool, error)
 	ResizeFS(target string) error
+	ResizeFSOffline(devicePath, fsType, dir string, mountOpts []string) (int64, error)
 	PathExists(path string) (bool, error)
 	NeedResize(devicePath string, deviceMountPath string) (bool, error)
+	LVDevicePath(lvUUID, vgUUID, lvName string) (string, error)
+	LVUUID(lvPath string) (string, error)
+	ActivateReadOnly(lvPath string) error
+	DeactivateReadOnly(lvPath string) error
+	OpenLUKS(devPath, volumeID string, passphrase []byte) (string, error)
+	CloseLUKS(volumeID string) error
+	ResizeLUKS(volumeID string, passphrase []byte) error
+	SyncLVTags(lvPath string, tags []string) error
+	FindMount(target string) (*mountutils.MountPoint, error)
+	ListMounts() ([]mountutils.MountPoint, error)
+	MountHolders(procDir, target string) ([]MountHolder, error)
+	LazyUnmount(target string) error
+	ThinPoolOutOfDataSpace(lvPath string) (bool, string, error)
+	ThinVolumeUsage(lvPath string) (used, poolFree uint64, thin bool, err error)
+	Trim(ctx context.Context, path string) (uint64, error)
+	ProbeXFSFeatures(dir string) (map[string]XFSSupport, error)
+	DiskFormat(device string) (string, error)
+	CheckFS(device, fsType, policy string) (FsckResult, string, error)
 }
 
+// ErrLVNotFound is returned by LVDevicePath and LVUUID when no LV matches.
+var ErrLVNotFound = errors.New("logical volume not found")
+
 type Store struct {
 	Log         logger.Logger
 	NodeStorage mountutils.SafeFormatAndMount
@@ -57,7 +83,11 @@ func NewStore(logger logger.Logger) *Store {
 	}
 }
 
-func (s *Store) NodeStageVolumeFS(source, target string, fsType string, mountOpts []string, formatOpts []string, lvmType, lvmThinPoolName string) error {
+// NodeStageVolumeFS formats source unless it holds a filesystem and mounts it
+// at target. The mount runs fsck -a on an ext filesystem first, unless checked
+// tells the filesystem has been checked according to the fsck policy of its
+// class.
+func (s *Store) NodeStageVolumeFS(source, target string, fsType string, mountOpts []string, formatOpts []string, lvmType, lvmThinPoolName string, checked bool) error {
 	log := s.Log.Named("NodeStageVolumeFS").With("source", source, "target", target)
 
 	log.Trace("format parameters", "fsType", fsType, "formatOptions", formatOpts)
@@ -114,6 +144,19 @@ func (s *Store) NodeStageVolumeFS(source, target string, fsType string, mountOpt
 	if lvmType == internal.LVMTypeThin {
 		log.Trace("thin volume", "thinPoolName", lvmThinPoolName)
 	}
+	if fsType == internal.FSTypeBtrfs {
+		if err := s.ensureUniqueBtrfsFSID(source); err != nil {
+			return fmt.Errorf("unable to check the btrfs fsid of %s: %w", source, err)
+		}
+	}
+	if checked {
+		// The options of FormatAndMount, which mounts with "defaults" too.
+		if err := s.NodeStorage.Mount(source, target, fsType, append(mountOpts, "defaults")); err != nil {
+			return fmt.Errorf("failed to mount: %w", err)
+		}
+		return nil
+	}
+
 	err = s.NodeStorage.FormatAndMountSensitiveWithFormatOptions(source, target, fsType, mountOpts, nil, formatOpts)
 	if err != nil {
 		return fmt.Errorf("failed to FormatAndMount : %w", err)
@@ -224,6 +267,9 @@ func (s *Store) IsNotMountPoint(target string) (bool, error) {
 	return notMounted, nil
 }
 
+// ResizeFS grows the filesystem mounted at mountTarget to its device, with
+// resize2fs for ext3 and ext4, xfs_growfs for xfs and btrfs filesystem resize
+// for btrfs, all of them online.
 func (s *Store) ResizeFS(mountTarget string) error {
 	log := s.Log.Named("ResizeFS").With("mountTarget", mountTarget)
 	devicePath, _, err := mountutils.GetDeviceNameFromMount(s.NodeStorage.Interface, mountTarget)
@@ -244,6 +290,56 @@ func (s *Store) ResizeFS(mountTarget string) error {
 	return nil
 }
 
+// ResizeFSOffline grows the fsType filesystem on devicePath, which is not
+// mounted, through a temporary mount in dir with mountOpts: xfs_growfs and
+// btrfs grow mounted filesystems only. It returns the size of the grown
+// filesystem.
+func (s *Store) ResizeFSOffline(devicePath, fsType, dir string, mountOpts []string) (int64, error) {
+	log := s.Log.Named("ResizeFSOffline").With("devicePath", devicePath)
+
+	if err := os.MkdirAll(dir, os.FileMode(0755)); err != nil {
+		return 0, fmt.Errorf("[MkdirAll] could not create directory %s: %w", dir, err)
+	}
+	target, err := os.MkdirTemp(dir, "resize-")
+	if err != nil {
+		return 0, fmt.Errorf("unable to create a temporary mount point in %s: %w", dir, err)
+	}
+	defer os.Remove(target)
+
+	if err := s.NodeStorage.Mount(devicePath, target, fsType, mountOpts); err != nil {
+		return 0, fmt.Errorf("unable to mount device %s at %s: %w", devicePath, target, err)
+	}
+	log.Info("mounted the device to resize its filesystem", "target", target, "fsType", fsType)
+
+	var size int64
+	_, err = mountutils.NewResizeFs(s.NodeStorage.Exec).Resize(devicePath, target)
+	if err == nil {
+		size, err = filesystemSize(target)
+	}
+	if unmountErr := s.NodeStorage.Unmount(target); unmountErr != nil {
+		log.Error("unable to unmount the temporary mount point", logger.Err(unmountErr), slog.String("target", target))
+		if err == nil {
+			err = fmt.Errorf("unable to unmount %s: %w", target, unmountErr)
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to resize the filesystem on device %s: %w", devicePath, err)
+	}
+
+	log.Info("filesystem resized successfully", "size", size)
+	return size, nil
+}
+
+// filesystemSize returns the size of the filesystem mounted at path, as df
+// reports it.
+func filesystemSize(path string) (int64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, fmt.Errorf("unable to statfs %s: %w", path, err)
+	}
+	return int64(st.Blocks) * st.Bsize, nil
+}
+
 func (s *Store) PathExists(path string) (bool, error) {
 	return mountutils.PathExists(path)
 }
@@ -252,6 +348,124 @@ func (s *Store) NeedResize(devicePath string, deviceMountPath string) (bool, err
 	return mountutils.NewResizeFs(s.NodeStorage.Exec).NeedResize(devicePath, deviceMountPath)
 }
 
+// LVDevicePath returns the device path of the LV with the UUID lvUUID or,
+// when lvUUID is empty, of the LV named lvName in the VG with the UUID vgUUID.
+// Unlike the names, the UUIDs survive a rename of the VG or of the LV, so the
+// path returned follows the LV under whatever names it has now.
+//
+// lvs runs with --readonly: it reads the metadata from the disks without taking
+// the LVM locks, which the agent of sds-node-configurator holds on the node.
+func (s *Store) LVDevicePath(lvUUID, vgUUID, lvName string) (string, error) {
+	selection := fmt.Sprintf("lv_uuid=%q", lvUUID)
+	if lvUUID == "" {
+		selection = fmt.Sprintf("vg_uuid=%q && lv_name=%q", vgUUID, lvName)
+	}
+
+	lines, err := s.lvs("lv_path", "--select", selection)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, "/dev/") {
+			return line, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrLVNotFound, selection)
+}
+
+// LVUUID returns the UUID of the LV at lvPath.
+func (s *Store) LVUUID(lvPath string) (string, error) {
+	lines, err := s.lvs("lv_uuid", lvPath)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range lines {
+		if lvmUUID.MatchString(line) {
+			return line, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrLVNotFound, lvPath)
+}
+
+// lvmUUID matches the UUIDs LVM prints: 32 characters in groups of
+// 6-4-4-4-4-4-6.
+var lvmUUID = regexp.MustCompile(`^[0-9A-Za-z]{6}(-[0-9A-Za-z]{4}){5}-[0-9A-Za-z]{6}$`)
+
+// lvs returns the lines lvs prints for field of the LVs args select, the
+// warnings it mixes into its output among them.
+func (s *Store) lvs(field string, args ...string) ([]string, error) {
+	args = append([]string{"--readonly", "--noheadings", "--options", field}, args...)
+	out, err := s.NodeStorage.Exec.Command("lvs", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the logical volumes: %w, output: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, nil
+}
+
+const (
+	// hostMountNamespace is the mount namespace of the host, seen through the
+	// /proc of the host mounted into the node plugin container.
+	hostMountNamespace = "/host/proc/1/ns/mnt"
+	// hostLVMPath is the static lvm binary sds-node-configurator installs on
+	// the nodes and runs all its commands with.
+	hostLVMPath = "/opt/deckhouse/sds/bin/lvm.static"
+)
+
+// hostLVM runs an LVM command that changes the metadata of an LV in the mount
+// namespace of the host, with the binary sds-node-configurator uses. The LVM
+// of the container would write its metadata backups to a read-only root and
+// take its locks in a /run/lock/lvm of its own, so nothing would keep it from
+// changing a VG the agent is changing at the same time.
+func (s *Store) hostLVM(command string, args ...string) ([]byte, error) {
+	args = append([]string{"--mount=" + hostMountNamespace, "--", hostLVMPath, command}, args...)
+	return s.NodeStorage.Exec.Command("nsenter", args...).CombinedOutput()
+}
+
+// ActivateReadOnly activates the LV at lvPath and makes its device read-only.
+// A thin snapshot is created with the activation skip flag, which is ignored
+// here. The read-only flag lives in the kernel only: the LV metadata is left
+// as it is, so the volumes restored from a snapshot stay writable.
+func (s *Store) ActivateReadOnly(lvPath string) error {
+	out, err := s.hostLVM("lvchange", "--activate", "y", "--ignoreactivationskip", lvPath)
+	if err != nil {
+		return fmt.Errorf("unable to activate %s: %w, output: %s", lvPath, err, strings.TrimSpace(string(out)))
+	}
+
+	out, err = s.NodeStorage.Exec.Command("blockdev", "--setro", lvPath).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to make %s read-only: %w, output: %s", lvPath, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// DeactivateReadOnly undoes ActivateReadOnly: the device of the LV at lvPath
+// is made writable again and the LV is deactivated, as the snapshot was
+// before it was staged.
+func (s *Store) DeactivateReadOnly(lvPath string) error {
+	out, err := s.NodeStorage.Exec.Command("blockdev", "--setrw", lvPath).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to make %s writable: %w, output: %s", lvPath, err, strings.TrimSpace(string(out)))
+	}
+
+	out, err = s.hostLVM("lvchange", "--activate", "n", lvPath)
+	if err != nil {
+		return fmt.Errorf("unable to deactivate %s: %w, output: %s", lvPath, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
 func toMapperPath(devPath string) string {
 	if !strings.HasPrefix(devPath, "/dev/") {
 		return ""
@@ -0,0 +1,51 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// trimmedRe matches the byte count fstrim --verbose prints, as in
+// "/mnt: 1 GiB (1073741824 bytes) trimmed".
+var trimmedRe = regexp.MustCompile(`\((\d+) bytes\) trimmed`)
+
+// Trim discards the unused blocks of the filesystem mounted at path and
+// returns the bytes fstrim reports as trimmed: the size of the ranges it has
+// discarded, mapped or not. On a thin LV the mapped blocks go back to the thin
+// pool. fstrim is killed once ctx is done.
+func (s *Store) Trim(ctx context.Context, path string) (uint64, error) {
+	out, err := s.NodeStorage.Exec.CommandContext(ctx, "fstrim", "--verbose", path).CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("fstrim failed: %w, output: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	m := trimmedRe.FindSubmatch(out)
+	if m == nil {
+		return 0, fmt.Errorf("unable to parse the fstrim output: %s", strings.TrimSpace(string(out)))
+	}
+	trimmed, err := strconv.ParseUint(string(m[1]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse the trimmed bytes %q: %w", m[1], err)
+	}
+
+	return trimmed, nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	mountutils "k8s.io/mount-utils"
+)
+
+// thinPoolOutOfDataSpace is the volume health character lvs reports in the
+// ninth position of lv_attr for a thin pool that has run out of data space.
+const thinPoolOutOfDataSpace = 'D'
+
+// FindMount returns the mount at target, nil if nothing is mounted there.
+func (s *Store) FindMount(target string) (*mountutils.MountPoint, error) {
+	mounts, err := s.NodeStorage.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the mounts: %w", err)
+	}
+
+	target = filepath.Clean(target)
+	// The last mount at a path is the one in effect.
+	for i := len(mounts) - 1; i >= 0; i-- {
+		if filepath.Clean(mounts[i].Path) == target {
+			return &mounts[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ListMounts returns the mounts of the node.
+func (s *Store) ListMounts() ([]mountutils.MountPoint, error) {
+	return s.NodeStorage.List()
+}
+
+// ThinPoolOutOfDataSpace reports whether the LV at lvPath is a thin volume
+// whose pool has run out of data space, and the name of that pool. A thick LV
+// has no pool and is never out of it.
+func (s *Store) ThinPoolOutOfDataSpace(lvPath string) (bool, string, error) {
+	fields, err := s.lvsFields(lvPath, "pool_lv,vg_name")
+	if err != nil {
+		return false, "", err
+	}
+	if fields[0] == "" {
+		return false, "", nil
+	}
+	pool := fields[1] + "/" + fields[0]
+
+	fields, err = s.lvsFields(pool, "lv_attr,data_percent")
+	if err != nil {
+		return false, pool, err
+	}
+
+	attr, dataPercent := fields[0], fields[1]
+	if len(attr) > 8 && attr[8] == thinPoolOutOfDataSpace {
+		return true, pool, nil
+	}
+	if percent, err := strconv.ParseFloat(dataPercent, 64); err == nil && percent >= 100 {
+		return true, pool, nil
+	}
+
+	return false, pool, nil
+}
+
+// ThinVolumeUsage returns the bytes the thin LV at lvPath has mapped in its
+// pool and the bytes left free in the pool. thin is false for a thick LV, for
+// which neither is known.
+func (s *Store) ThinVolumeUsage(lvPath string) (used, poolFree uint64, thin bool, err error) {
+	fields, err := s.lvsFields(lvPath, "pool_lv,vg_name,lv_size,data_percent", "--units", "b", "--nosuffix")
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if fields[0] == "" {
+		return 0, 0, false, nil
+	}
+	pool := fields[1] + "/" + fields[0]
+
+	used, err = percentOf(fields[2], fields[3])
+	if err != nil {
+		return 0, 0, true, fmt.Errorf("unable to get the usage of %s: %w", lvPath, err)
+	}
+
+	poolFree, err = s.thinPoolFree(pool)
+	if err != nil {
+		return 0, 0, true, err
+	}
+
+	return used, poolFree, true, nil
+}
+
+// thinPoolFree returns the bytes of the thin pool vg/pool no thin LV has
+// mapped.
+func (s *Store) thinPoolFree(pool string) (uint64, error) {
+	fields, err := s.lvsFields(pool, "lv_size,data_percent", "--units", "b", "--nosuffix")
+	if err != nil {
+		return 0, err
+	}
+	size, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse the size of %s: %w", pool, err)
+	}
+	used, err := percentOf(fields[0], fields[1])
+	if err != nil {
+		return 0, fmt.Errorf("unable to get the usage of %s: %w", pool, err)
+	}
+
+	return size - min(used, size), nil
+}
+
+// percentOf returns the data_percent percent of the lv_size size, both as lvs
+// prints them.
+func percentOf(size, percent string) (uint64, error) {
+	bytes, err := strconv.ParseUint(size, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse the size %q: %w", size, err)
+	}
+	p, err := strconv.ParseFloat(percent, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse the data percent %q: %w", percent, err)
+	}
+
+	return uint64(float64(bytes) * p / 100), nil
+}
+
+// lvsFields returns the given comma-separated fields of one LV, in order.
+// extraArgs go to lvs as they are, such as the units of the sizes.
+func (s *Store) lvsFields(lv, fields string, extraArgs ...string) ([]string, error) {
+	want := len(strings.Split(fields, ","))
+	args := append([]string{"--readonly", "--noheadings", "--separator", "|", "--options", fields}, extraArgs...)
+	out, err := s.NodeStorage.Exec.Command("lvs", append(args, lv)...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %s of %s: %w, output: %s", fields, lv, err, strings.TrimSpace(string(out)))
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		values := strings.Split(strings.TrimSpace(line), "|")
+		if len(values) == want {
+			for i := range values {
+				values[i] = strings.TrimSpace(values[i])
+			}
+			return values, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unable to parse the %s of %s from %q", fields, lv, strings.TrimSpace(string(out)))
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// XFSFeature is an on-disk feature of xfs that a recent mkfs.xfs turns on by
+// default and that an older kernel refuses to mount.
+type XFSFeature struct {
+	Name string
+	// Option is the mkfs.xfs option the feature is a suboption of.
+	Option string
+}
+
+// XFSFeatures are the features probed on the nodes.
+var XFSFeatures = []XFSFeature{
+	{Name: "bigtime", Option: "-m"},
+	{Name: "inobtcount", Option: "-m"},
+	{Name: "reflink", Option: "-m"},
+	{Name: "nrext64", Option: "-i"},
+}
+
+// XFSSupport tells whether the mkfs.xfs of a node knows a feature and whether
+// its kernel mounts a filesystem with the feature on.
+type XFSSupport struct {
+	Mkfs   bool
+	Kernel bool
+}
+
+// xfsProbeImageSize is the size of the sparse image the features are probed
+// on, above the 300 MiB xfs needs at least.
+const xfsProbeImageSize = 512 << 20
+
+// ProbeXFSFeatures probes the XFSFeatures on the node in dir, which is removed
+// afterwards. mkfs.xfs is asked to plan a filesystem with each feature, and
+// an image formatted with each feature mkfs.xfs knows is loop-mounted.
+//
+// A kernel that cannot loop-mount even an image with none of the features
+// tells nothing, so the error returned then comes with every feature reported
+// as not mountable.
+func (s *Store) ProbeXFSFeatures(dir string) (map[string]XFSSupport, error) {
+	log := s.Log.Named("ProbeXFSFeatures")
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create %s: %w", dir, err)
+	}
+	defer os.RemoveAll(dir)
+
+	image := filepath.Join(dir, "xfs.img")
+	f, err := os.Create(image)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the image: %w", err)
+	}
+	err = f.Truncate(xfsProbeImageSize)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("unable to size the image: %w", err)
+	}
+
+	support := make(map[string]XFSSupport, len(XFSFeatures))
+	for _, feature := range XFSFeatures {
+		err := s.mkfsXFS("-N", "-f", feature.Option, feature.Name+"=1", image)
+		log.Debug("probed mkfs.xfs", "feature", feature.Name, "supported", err == nil)
+		support[feature.Name] = XFSSupport{Mkfs: err == nil}
+	}
+
+	if err := s.probeXFSMount(image, filepath.Join(dir, "mnt"), support, ""); err != nil {
+		return support, fmt.Errorf("unable to mount an xfs image without the features: %w", err)
+	}
+	for _, feature := range XFSFeatures {
+		if !support[feature.Name].Mkfs {
+			continue
+		}
+		err := s.probeXFSMount(image, filepath.Join(dir, "mnt-"+feature.Name), support, feature.Name)
+		log.Debug("probed the kernel", "feature", feature.Name, "supported", err == nil)
+		support[feature.Name] = XFSSupport{Mkfs: true, Kernel: err == nil}
+	}
+
+	return support, nil
+}
+
+// probeXFSMount formats image with the feature on, if any, and the other
+// features mkfs.xfs knows off, and mounts it read-only at target.
+func (s *Store) probeXFSMount(image, target string, support map[string]XFSSupport, on string) error {
+	values := make(map[string]bool, len(support))
+	for name, feature := range support {
+		if feature.Mkfs {
+			values[name] = name == on
+		}
+	}
+	if err := s.mkfsXFS(append(append([]string{"-f"}, XFSFeatureArgs(values)...), image)...); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(target, 0o700); err != nil {
+		return fmt.Errorf("unable to create %s: %w", target, err)
+	}
+	if err := s.NodeStorage.Mount(image, target, "xfs", []string{"loop", "ro"}); err != nil {
+		return err
+	}
+	return s.NodeStorage.Unmount(target)
+}
+
+func (s *Store) mkfsXFS(args ...string) error {
+	out, err := s.NodeStorage.Exec.Command("mkfs.xfs", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("mkfs.xfs failed: %w, output: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// XFSFeatureArgs returns the mkfs.xfs options turning the features in values
+// on or off.
+func XFSFeatureArgs(values map[string]bool) []string {
+	var options []string
+	suboptions := map[string][]string{}
+	for _, feature := range XFSFeatures {
+		on, ok := values[feature.Name]
+		if !ok {
+			continue
+		}
+		if _, seen := suboptions[feature.Option]; !seen {
+			options = append(options, feature.Option)
+		}
+		value := "0"
+		if on {
+			value = "1"
+		}
+		suboptions[feature.Option] = append(suboptions[feature.Option], feature.Name+"="+value)
+	}
+
+	args := make([]string, 0, 2*len(options))
+	for _, option := range options {
+		args = append(args, option, strings.Join(suboptions[option], ","))
+	}
+	return args
+}
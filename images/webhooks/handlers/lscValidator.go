@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
 
 	"github.com/slok/kubewebhook/v2/pkg/model"
 	kwhvalidating "github.com/slok/kubewebhook/v2/pkg/webhook/validating"
@@ -27,6 +28,8 @@ import (
 
 	slv "github.com/deckhouse/sds-local-volume/api/v1alpha1"
 	"github.com/deckhouse/sds-local-volume/images/webhooks/pkg/logger"
+	"github.com/deckhouse/sds-local-volume/lib/go/common/pkg/fsoptions"
+	"github.com/deckhouse/sds-local-volume/lib/go/common/pkg/lvname"
 	snc "github.com/deckhouse/sds-node-configurator/api/v1alpha1"
 )
 
@@ -62,6 +65,51 @@ func LSCValidate(log logger.Logger) kwhvalidating.ValidatorFunc {
 			return &kwhvalidating.ValidatorResult{Valid: false, Message: errMsg}, nil
 		}
 
+		if lsc.Spec.LVM.LVNameTemplate != "" {
+			if _, err := lvname.Parse(lsc.Spec.LVM.LVNameTemplate); err != nil {
+				errMsg := fmt.Sprintf("Invalid spec.lvm.lvNameTemplate: %s", err.Error())
+				log.Info(errMsg)
+				return &kwhvalidating.ValidatorResult{Valid: false, Message: errMsg}, nil
+			}
+		}
+
+		fsType := lsc.Spec.FSType
+		if fsType == "" {
+			fsType = "ext4"
+		}
+		if !slices.Contains(fsoptions.FSTypes, fsType) {
+			errMsg := fmt.Sprintf("Unsupported spec.fsType %s, supported are %s", fsType, strings.Join(fsoptions.FSTypes, ", "))
+			log.Info(errMsg)
+			return &kwhvalidating.ValidatorResult{Valid: false, Message: errMsg}, nil
+		}
+		if len(lsc.Spec.MkfsOptions) > 0 {
+			if _, err := fsoptions.MkfsArgs(fsType, strings.Join(lsc.Spec.MkfsOptions, " ")); err != nil {
+				errMsg := fmt.Sprintf("Invalid spec.mkfsOptions: %s", err.Error())
+				log.Info(errMsg)
+				return &kwhvalidating.ValidatorResult{Valid: false, Message: errMsg}, nil
+			}
+		}
+		if len(lsc.Spec.MountOptions) > 0 {
+			if err := fsoptions.ValidateMountOptions(fsType, lsc.Spec.MountOptions); err != nil {
+				errMsg := fmt.Sprintf("Invalid spec.mountOptions: %s", err.Error())
+				log.Info(errMsg)
+				return &kwhvalidating.ValidatorResult{Valid: false, Message: errMsg}, nil
+			}
+		}
+
+		if as := lsc.Spec.Autoscaling; as != nil {
+			if as.Increment.Sign() <= 0 {
+				errMsg := "Field spec.autoscaling.increment must be positive"
+				log.Info(errMsg)
+				return &kwhvalidating.ValidatorResult{Valid: false, Message: errMsg}, nil
+			}
+			if as.MaxSize.Cmp(as.Increment) <= 0 {
+				errMsg := fmt.Sprintf("Field spec.autoscaling.maxSize (%s) must be larger than spec.autoscaling.increment (%s)", as.MaxSize.String(), as.Increment.String())
+				log.Info(errMsg)
+				return &kwhvalidating.ValidatorResult{Valid: false, Message: errMsg}, nil
+			}
+		}
+
 		listDevice := &snc.LVMVolumeGroupList{}
 
 		err = cl.List(ctx, listDevice)
@@ -0,0 +1,231 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fsoptions holds the mkfs and mount options a LocalStorageClass may
+// set with spec.mkfsOptions and spec.mountOptions, by filesystem type. The
+// webhook validates the options with it, and the CSI driver checks the mkfs
+// options again before it runs mkfs as root with them.
+//
+// Options that would break the driver, such as a label or a UUID of its own
+// for every volume or a read-only mount, are left out on purpose.
+package fsoptions
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// FSTypes are the filesystem types a LocalStorageClass can have.
+var FSTypes = []string{"ext4", "ext3", "xfs", "btrfs"}
+
+// valueFunc tells whether value is valid for an option. A nil one means the
+// option takes no value.
+type valueFunc func(value string) bool
+
+var (
+	numberRe = regexp.MustCompile(`^[0-9]+$`)
+	// wordRe is a value such as a size with a unit or a list of suboptions.
+	wordRe = regexp.MustCompile(`^[A-Za-z0-9_.,=:^+-]+$`)
+)
+
+func number(value string) bool { return numberRe.MatchString(value) }
+
+func word(value string) bool { return wordRe.MatchString(value) }
+
+func oneOf(values ...string) valueFunc {
+	return func(value string) bool { return slices.Contains(values, value) }
+}
+
+// extMkfsFlags are the flags of mke2fs, which formats ext3 and ext4.
+var extMkfsFlags = map[string]valueFunc{
+	"-b": number,
+	"-E": word,
+	"-i": number,
+	"-I": number,
+	"-m": number,
+	"-N": number,
+	"-O": word,
+	"-T": word,
+}
+
+// mkfsFlags are the flags of mkfs.<fsType> allowed, with their values.
+var mkfsFlags = map[string]map[string]valueFunc{
+	"ext4": extMkfsFlags,
+	"ext3": extMkfsFlags,
+	"xfs": {
+		"-b": word,
+		"-d": word,
+		"-i": word,
+		"-K": nil,
+		"-l": word,
+		"-m": word,
+		"-n": word,
+		"-s": word,
+	},
+	"btrfs": {
+		"-d": oneOf("single", "dup"),
+		"-m": oneOf("single", "dup"),
+		"-n": word,
+		"-s": word,
+		"-O": word,
+		"-R": word,
+		"-K": nil,
+	},
+}
+
+// commonMountOptions are allowed for every filesystem type.
+var commonMountOptions = map[string]valueFunc{
+	"noatime":     nil,
+	"nodiratime":  nil,
+	"relatime":    nil,
+	"strictatime": nil,
+	"lazytime":    nil,
+	"nolazytime":  nil,
+	"discard":     nil,
+	"nodiscard":   nil,
+	"nodev":       nil,
+	"nosuid":      nil,
+	"noexec":      nil,
+	"sync":        nil,
+	"dirsync":     nil,
+}
+
+// extMountOptions are the options of the ext4 driver of the kernel, which
+// mounts ext3 too.
+var extMountOptions = map[string]valueFunc{
+	"data":                 oneOf("journal", "ordered", "writeback"),
+	"commit":               number,
+	"errors":               oneOf("continue", "remount-ro", "panic"),
+	"barrier":              oneOf("0", "1"),
+	"nobarrier":            nil,
+	"journal_checksum":     nil,
+	"journal_async_commit": nil,
+	"auto_da_alloc":        nil,
+	"noauto_da_alloc":      nil,
+	"delalloc":             nil,
+	"nodelalloc":           nil,
+	"dioread_lock":         nil,
+	"dioread_nolock":       nil,
+	"stripe":               number,
+	"inode_readahead_blks": number,
+	"max_batch_time":       number,
+	"min_batch_time":       number,
+	"init_itable":          number,
+	"noinit_itable":        nil,
+	"block_validity":       nil,
+	"noblock_validity":     nil,
+	"nombcache":            nil,
+}
+
+// mountOptions are the options allowed for one filesystem type on top of
+// commonMountOptions.
+var mountOptions = map[string]map[string]valueFunc{
+	"ext4": extMountOptions,
+	"ext3": extMountOptions,
+	"xfs": {
+		"allocsize":   word,
+		"inode32":     nil,
+		"inode64":     nil,
+		"largeio":     nil,
+		"nolargeio":   nil,
+		"logbufs":     number,
+		"logbsize":    word,
+		"noalign":     nil,
+		"swalloc":     nil,
+		"sunit":       number,
+		"swidth":      number,
+		"wsync":       nil,
+		"filestreams": nil,
+		"nouuid":      nil,
+	},
+	"btrfs": {
+		"compress":       word,
+		"compress-force": word,
+		"autodefrag":     nil,
+		"noautodefrag":   nil,
+		"commit":         number,
+		"space_cache":    oneOf("v2"),
+		"ssd":            nil,
+		"nossd":          nil,
+		"ssd_spread":     nil,
+		"datacow":        nil,
+		"nodatacow":      nil,
+		"datasum":        nil,
+		"nodatasum":      nil,
+		"flushoncommit":  nil,
+		"max_inline":     word,
+		"thread_pool":    number,
+	},
+}
+
+// MkfsArgs splits the mkfs options of a filesystem type, a flag and its value
+// at a time separated by spaces, into the arguments of mkfs, and checks them
+// against the flags allowed.
+func MkfsArgs(fsType, options string) ([]string, error) {
+	flags, ok := mkfsFlags[fsType]
+	if !ok {
+		return nil, fmt.Errorf("no mkfs options are supported for %s", fsType)
+	}
+
+	args := strings.Fields(options)
+	for i := 0; i < len(args); i++ {
+		valid, ok := flags[args[i]]
+		if !ok {
+			return nil, fmt.Errorf("mkfs option %s is not supported for %s", args[i], fsType)
+		}
+		if valid == nil {
+			continue
+		}
+
+		i++
+		if i == len(args) || !valid(args[i]) {
+			return nil, fmt.Errorf("mkfs option %s of %s needs a valid value", args[i-1], fsType)
+		}
+	}
+
+	return args, nil
+}
+
+// ValidateMountOptions checks mount options, each an option or an
+// option=value, against the options allowed for a filesystem type.
+func ValidateMountOptions(fsType string, options []string) error {
+	allowed, ok := mountOptions[fsType]
+	if !ok {
+		return fmt.Errorf("no mount options are supported for %s", fsType)
+	}
+
+	for _, option := range options {
+		name, value, hasValue := strings.Cut(option, "=")
+		valid, ok := allowed[name]
+		if !ok {
+			valid, ok = commonMountOptions[name]
+		}
+		if !ok {
+			return fmt.Errorf("mount option %s is not supported for %s", name, fsType)
+		}
+
+		switch {
+		case valid == nil && hasValue:
+			return fmt.Errorf("mount option %s takes no value", name)
+		case valid != nil && (!hasValue || !valid(value)):
+			return fmt.Errorf("mount option %s needs a valid value", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fsoptions
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMkfsArgs(t *testing.T) {
+	for _, tc := range []struct {
+		fsType  string
+		options string
+		want    []string
+		wantErr bool
+	}{
+		{fsType: "ext4", options: "-m 0 -O ^has_journal", want: []string{"-m", "0", "-O", "^has_journal"}},
+		{fsType: "xfs", options: "-n ftype=1 -K", want: []string{"-n", "ftype=1", "-K"}},
+		{fsType: "ext3", options: "-m 1", want: []string{"-m", "1"}},
+		{fsType: "btrfs", options: "-m dup -O quota", want: []string{"-m", "dup", "-O", "quota"}},
+		{fsType: "btrfs", options: "-d raid0", wantErr: true},
+		{fsType: "ext4", options: "", want: []string{}},
+		{fsType: "ext4", options: "-L data", wantErr: true},
+		{fsType: "ext4", options: "-m", wantErr: true},
+		{fsType: "ext4", options: "-m zero", wantErr: true},
+		{fsType: "xfs", options: "-n ftype=1;reboot", wantErr: true},
+		{fsType: "vfat", options: "-F 32", wantErr: true},
+	} {
+		t.Run(tc.fsType+" "+tc.options, func(t *testing.T) {
+			got, err := MkfsArgs(tc.fsType, tc.options)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("MkfsArgs = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MkfsArgs: %v", err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("MkfsArgs = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateMountOptions(t *testing.T) {
+	for _, tc := range []struct {
+		fsType  string
+		options []string
+		wantErr bool
+	}{
+		{fsType: "ext4", options: []string{"noatime", "discard", "data=writeback", "commit=30"}},
+		{fsType: "xfs", options: []string{"noatime", "logbsize=256k", "inode64"}},
+		{fsType: "btrfs", options: []string{"compress=zstd:3", "noatime", "space_cache=v2"}},
+		{fsType: "btrfs", options: []string{"subvol=/other"}, wantErr: true},
+		{fsType: "xfs", options: []string{"data=writeback"}, wantErr: true},
+		{fsType: "ext4", options: []string{"ro"}, wantErr: true},
+		{fsType: "ext4", options: []string{"noatime=1"}, wantErr: true},
+		{fsType: "ext4", options: []string{"commit"}, wantErr: true},
+		{fsType: "ext4", options: []string{"errors=reboot"}, wantErr: true},
+	} {
+		err := ValidateMountOptions(tc.fsType, tc.options)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ValidateMountOptions(%s, %q) = %v, want an error: %v", tc.fsType, tc.options, err, tc.wantErr)
+		}
+	}
+}
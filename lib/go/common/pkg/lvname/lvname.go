@@ -0,0 +1,177 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lvname renders the names of the logical volumes created on the
+// nodes from the template set in LocalStorageClass spec.lvm.lvNameTemplate.
+// The webhook validates the template with it and the CSI driver renders it,
+// so both agree on what a valid template is.
+package lvname
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// MaxLength is the longest LV name a template may produce. LVM itself allows
+// more, but the device-mapper name of an LV is "<vg>-<lv>" with every dash
+// doubled and is limited to 127 bytes, and the VG name takes its share too.
+const MaxLength = 63
+
+// shortLength is the length of Values.Short.
+const shortLength = 8
+
+// Prefixes and substrings LVM reserves for its own volumes, see lvm(8) "VALID
+// NAMES". An LV named after them cannot be created.
+var (
+	reservedPrefixes   = []string{"snapshot", "pvmove"}
+	reservedSubstrings = []string{
+		"_cdata", "_cmeta", "_corig", "_cpool", "_cvol", "_imeta", "_iorig", "_mimage",
+		"_mlog", "_pmspare", "_rimage", "_rmeta", "_tdata", "_tmeta", "_vdata", "_vorigin",
+		"_wcorig",
+	}
+)
+
+// Values are what a template can refer to.
+type Values struct {
+	// Namespace and PVCName name the PersistentVolumeClaim of the volume.
+	Namespace string
+	PVCName   string
+	// PVName is the name of the PersistentVolume, which is also the name of
+	// the LVMLogicalVolume.
+	PVName string
+	// Short is a short identifier derived from PVName: the first characters of
+	// the UUID in "pvc-<uuid>", or of a hash of any other name.
+	Short string
+}
+
+// NewValues returns the values for the volume of the given claim and PV.
+func NewValues(namespace, pvcName, pvName string) Values {
+	return Values{
+		Namespace: namespace,
+		PVCName:   pvcName,
+		PVName:    pvName,
+		Short:     short(pvName),
+	}
+}
+
+func short(pvName string) string {
+	if id, ok := strings.CutPrefix(pvName, "pvc-"); ok && len(id) >= shortLength {
+		if _, err := hex.DecodeString(id[:shortLength]); err == nil {
+			return id[:shortLength]
+		}
+	}
+
+	sum := sha256.Sum256([]byte(pvName))
+	return hex.EncodeToString(sum[:])[:shortLength]
+}
+
+// Template is a parsed and validated LV name template.
+type Template struct {
+	tmpl *template.Template
+}
+
+// Parse parses text as an LV name template and checks that it produces valid
+// names that are distinct for distinct PersistentVolumes, which means it must
+// refer to .PVName or .Short: a claim deleted and created again under the same
+// name gets a new volume, and its LV must not collide with a retained one.
+func Parse(text string) (*Template, error) {
+	tmpl, err := template.New("lvName").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	t := &Template{tmpl: tmpl}
+
+	first, err := t.Render(NewValues("default", "data", "pvc-0a1b2c3d-0000-0000-0000-000000000000"))
+	if err != nil {
+		return nil, err
+	}
+	second, err := t.Render(NewValues("default", "data", "pvc-4e5f6a7b-0000-0000-0000-000000000000"))
+	if err != nil {
+		return nil, err
+	}
+	if first == second {
+		return nil, errors.New("the template must refer to .PVName or .Short, so that every PersistentVolume gets an LV of its own")
+	}
+
+	return t, nil
+}
+
+// Render returns the LV name for values. A name longer than MaxLength is cut
+// and ends with values.Short, which keeps it unique.
+func (t *Template) Render(values Values) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, values); err != nil {
+		return "", err
+	}
+
+	name := b.String()
+	if len(name) > MaxLength {
+		name = name[:MaxLength-len(values.Short)-1] + "-" + values.Short
+	}
+
+	if err := Validate(name); err != nil {
+		return "", fmt.Errorf("the template renders %q: %w", name, err)
+	}
+
+	return name, nil
+}
+
+// Validate checks name against the rules LVM has for LV names and MaxLength.
+func Validate(name string) error {
+	switch {
+	case name == "":
+		return errors.New("the name is empty")
+	case len(name) > MaxLength:
+		return fmt.Errorf("the name is longer than %d characters", MaxLength)
+	case name == "." || name == "..":
+		return fmt.Errorf("the name must not be %q", name)
+	case name[0] == '-':
+		return errors.New("the name must not start with '-'")
+	}
+
+	for _, r := range name {
+		if !isValidRune(r) {
+			return fmt.Errorf("the name contains %q, only the characters a-z, A-Z, 0-9, '+', '_', '.' and '-' are allowed", r)
+		}
+	}
+
+	for _, prefix := range reservedPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return fmt.Errorf("the name must not start with %q, LVM reserves it", prefix)
+		}
+	}
+	for _, substring := range reservedSubstrings {
+		if strings.Contains(name, substring) {
+			return fmt.Errorf("the name must not contain %q, LVM reserves it", substring)
+		}
+	}
+
+	return nil
+}
+
+func isValidRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case r == '+', r == '_', r == '.', r == '-':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2026 Flant JSC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lvname
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	values := NewValues("db", "data-postgres-0", "pvc-3f1c9a2e-5b7d-4c1e-9f00-0123456789ab")
+
+	for _, tc := range []struct {
+		template string
+		want     string
+	}{
+		{template: "{{.Namespace}}-{{.PVCName}}-{{.Short}}", want: "db-data-postgres-0-3f1c9a2e"},
+		{template: "fast-{{.PVName}}", want: "fast-pvc-3f1c9a2e-5b7d-4c1e-9f00-0123456789ab"},
+	} {
+		t.Run(tc.template, func(t *testing.T) {
+			tmpl, err := Parse(tc.template)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			got, err := tmpl.Render(values)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Render = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRenderCutsLongNames(t *testing.T) {
+	tmpl, err := Parse("{{.Namespace}}-{{.PVCName}}-{{.Short}}")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	values := NewValues("db", strings.Repeat("a", 100), "pvc-3f1c9a2e-5b7d-4c1e-9f00-0123456789ab")
+	got, err := tmpl.Render(values)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if len(got) != MaxLength || !strings.HasSuffix(got, "-3f1c9a2e") {
+		t.Errorf("Render = %q, want %d characters ending with the short id", got, MaxLength)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for name, text := range map[string]string{
+		"not unique":        "{{.Namespace}}-{{.PVCName}}",
+		"fixed name":        "data",
+		"unknown field":     "{{.Node}}-{{.Short}}",
+		"invalid character": "{{.Namespace}}/{{.Short}}",
+		"reserved prefix":   "snapshot-{{.Short}}",
+		"reserved suffix":   "{{.Short}}_tmeta",
+		"leading dash":      "-{{.Short}}",
+		"syntax error":      "{{.Short",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(text); err == nil {
+				t.Errorf("Parse(%q) succeeded, want an error", text)
+			}
+		})
+	}
+}
+
+func TestShortOfANameWithoutUUID(t *testing.T) {
+	got := NewValues("", "", "my-volume").Short
+	if len(got) != shortLength || got == NewValues("", "", "my-volume-2").Short {
+		t.Errorf("Short = %q, want %d characters distinct for distinct names", got, shortLength)
+	}
+}